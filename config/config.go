@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/jmkng/onyx/convert/json"
+	"github.com/jmkng/onyx/convert/md"
 	"github.com/jmkng/onyx/convert/yaml"
 )
 
@@ -37,6 +38,11 @@ var Names = []string{
 // Options describes all of the values that might be found in a project's
 // configuration file.
 type Options struct {
+	// Title is the name of the site.
+	Title string `json:"title" yaml:"title"`
+	// BaseURL is the absolute URL that the site is hosted at, such as
+	// `https://example.com`, used wherever an absolute link is required.
+	BaseURL string `json:"baseURL" yaml:"baseURL"`
 	// Output controls the location that the resulting files are placed.
 	// This directory is the
 	Output string `json:"output" yaml:"output"`
@@ -44,9 +50,37 @@ type Options struct {
 	Verbose bool `json:"verbose" yaml:"verbose"`
 	// Ignore provides an obvious way to ignore
 	Ignore ignore `json:"ignore" yaml:"ignore"`
+	// Permalinks maps the name of a group to the pattern used to build the
+	// links of its members, such as `/:year/:month/:slug/`. Recognized
+	// placeholders are `:year`, `:month`, `:day`, `:slug`, `:filename` and
+	// `:section`.
+	Permalinks map[string]string `json:"permalinks" yaml:"permalinks"`
+	// Markdown controls the extensions and renderer options used to convert
+	// markdown resources.
+	Markdown md.Options `json:"markdown" yaml:"markdown"`
+	// Paginate lists routes that display the members of a group across
+	// multiple pages. Front matter in the route takes precedence.
+	Paginate []paginate `json:"paginate" yaml:"paginate"`
+	// Taxonomies lists the front matter keys used to classify resources,
+	// such as `tags` or `categories`. A page is generated for each taxonomy
+	// and each term within it.
+	Taxonomies []taxonomy `json:"taxonomies" yaml:"taxonomies"`
+	// Feeds controls the RSS, Atom and JSON feeds written for each group.
+	Feeds feeds `json:"feeds" yaml:"feeds"`
+	// Sitemap controls the sitemap written when BaseURL is set.
+	Sitemap sitemap `json:"sitemap" yaml:"sitemap"`
+	// Robots controls the robots.txt written when BaseURL is set.
+	Robots robots `json:"robots" yaml:"robots"`
+	// Redirects names the host that a redirects file is written for, using the
+	// `aliases` found in front matter. Recognized hosts are `netlify`, `nginx`
+	// and `apache`.
+	Redirects string `json:"redirects" yaml:"redirects"`
+	// Preserve lists glob patterns, relative to the output directory, that match
+	// files which are never removed from the output directory, such as `CNAME`
+	// or `.well-known/*`.
+	Preserve []string `json:"preserve" yaml:"preserve"`
 	// These aren't supported yet, so better comment them out for now.
 	// Domains  []string `json:"domains" yaml:"domains"`
-	// Preserve []string `json:"preserve" yaml:"preserve"`
 }
 
 type ignore struct {
@@ -54,6 +88,103 @@ type ignore struct {
 	Suffix string
 }
 
+type paginate struct {
+	// Route is the path to the listing route, relative to the routes directory.
+	Route string `json:"route" yaml:"route"`
+	// Group is the name of the group being listed.
+	Group string `json:"group" yaml:"group"`
+	// PerPage is the maximum number of group members on each page.
+	PerPage int `json:"perPage" yaml:"perPage"`
+}
+
+type sitemap struct {
+	// Disabled prevents the sitemap from being written.
+	Disabled bool `json:"disabled" yaml:"disabled"`
+}
+
+type robots struct {
+	// Disabled prevents robots.txt from being written.
+	Disabled bool `json:"disabled" yaml:"disabled"`
+	// UserAgent is the crawler the rules apply to, which defaults to `*`.
+	UserAgent string `json:"userAgent" yaml:"userAgent"`
+	// Allow lists paths that crawlers may visit.
+	Allow []string `json:"allow" yaml:"allow"`
+	// Disallow lists paths that crawlers are asked not to visit.
+	Disallow []string `json:"disallow" yaml:"disallow"`
+}
+
+type feeds struct {
+	// Formats lists the feeds written for each group, and may contain `rss`,
+	// `atom` and `json`. No feeds are written if it is empty.
+	Formats []string `json:"formats" yaml:"formats"`
+	// Limit is the maximum number of items in each feed, or 0 for no limit.
+	Limit int `json:"limit" yaml:"limit"`
+	// Full determines if items hold the full content of a resource instead
+	// of a summary.
+	Full bool `json:"full" yaml:"full"`
+	// Taxonomies determines if feeds are also written for every taxonomy term.
+	Taxonomies bool `json:"taxonomies" yaml:"taxonomies"`
+	// Groups overrides the options above for individual groups by name.
+	Groups map[string]Feed `json:"groups" yaml:"groups"`
+}
+
+// Feed holds the options of the feeds written for a single group.
+type Feed struct {
+	// Enabled determines if feeds are written for the group, and defaults to true.
+	Enabled *bool `json:"enabled" yaml:"enabled"`
+	// Limit is the maximum number of items in each feed, or 0 for no limit, and
+	// defaults to the limit shared by every group.
+	Limit *int `json:"limit" yaml:"limit"`
+	// Full determines if items hold the full content of a resource instead
+	// of a summary, and defaults to the option shared by every group.
+	Full *bool `json:"full" yaml:"full"`
+}
+
+// Feed will return the feed options for the named group. Options configured for
+// the group override the options shared by every group, and every field of the
+// result is set.
+func (f feeds) Feed(group string) Feed {
+	enabled := true
+	limit := f.Limit
+	full := f.Full
+
+	result := Feed{
+		Enabled: &enabled,
+		Limit:   &limit,
+		Full:    &full,
+	}
+
+	override, ok := f.Groups[group]
+	if !ok {
+		return result
+	}
+
+	if override.Enabled != nil {
+		result.Enabled = override.Enabled
+	}
+
+	if override.Limit != nil {
+		result.Limit = override.Limit
+	}
+
+	if override.Full != nil {
+		result.Full = override.Full
+	}
+
+	return result
+}
+
+type taxonomy struct {
+	// Name is the front matter key holding the terms of the taxonomy.
+	Name string `json:"name" yaml:"name"`
+	// IndexTemplate renders the page listing every term, and defaults to
+	// `taxonomy.tmpl` if that file exists.
+	IndexTemplate string `json:"indexTemplate" yaml:"indexTemplate"`
+	// TermTemplate renders the page listing every resource with a term, and
+	// defaults to `term.tmpl` if that file exists.
+	TermTemplate string `json:"termTemplate" yaml:"termTemplate"`
+}
+
 // SetState will read the file at the given path and unmarshal the contents into
 // config.State. An error is returned if the file is malformed or cannot be read.
 func SetState(path string) error {
@@ -64,11 +195,15 @@ func SetState(path string) error {
 		return fmt.Errorf("failed to read configuration file: %v", filepath.Base(path))
 	}
 
+	// The file may be read more than once in a long-running routine, so values
+	// are unmarshaled into a fresh instance to avoid keeping removed options.
+	var next Options
+
 	switch ext {
 	case ".json":
-		err = json.Unmarshal(bytes, &State)
+		err = json.Unmarshal(bytes, &next)
 	case ".yaml", ".yml":
-		err = yaml.Unmarshal(bytes, &State)
+		err = yaml.Unmarshal(bytes, &next)
 	default:
 		return fmt.Errorf("attempted to unmarshal unrecognized configuration file type: %v", filepath.Base(path))
 	}
@@ -77,6 +212,8 @@ func SetState(path string) error {
 		return err
 	}
 
+	State = next
+
 	return nil
 }
 
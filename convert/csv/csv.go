@@ -0,0 +1,36 @@
+package csv
+
+import (
+	"bytes"
+	_csv "encoding/csv"
+)
+
+// Unmarshal reads CSV records from data into out. The first record is treated
+// as a header, and every following record is stored as a map from header
+// field to value.
+func Unmarshal(data []byte, out *[]map[string]string) error {
+	records, err := _csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return err
+	}
+
+	result := []map[string]string{}
+
+	if len(records) > 0 {
+		header := records[0]
+
+		for _, record := range records[1:] {
+			row := make(map[string]string, len(header))
+
+			for i, field := range header {
+				row[field] = record[i]
+			}
+
+			result = append(result, row)
+		}
+	}
+
+	*out = result
+
+	return nil
+}
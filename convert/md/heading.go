@@ -0,0 +1,214 @@
+package md
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Anchors controls the permalink anchor appended to every heading.
+type Anchors struct {
+	// Enabled appends an anchor linking to each heading.
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Text is the text of the anchor, which is "#" if no text is configured.
+	Text string `json:"text" yaml:"text"`
+	// Class is the class of the anchor, which is "anchor" if no class is configured.
+	Class string `json:"class" yaml:"class"`
+}
+
+// TOC controls the levels of the headings found in the table of contents.
+type TOC struct {
+	// StartLevel is the lowest heading level found in the table of contents,
+	// which is 2 if no level is configured.
+	StartLevel int `json:"startLevel" yaml:"startLevel"`
+	// EndLevel is the highest heading level found in the table of contents,
+	// which is 3 if no level is configured.
+	EndLevel int `json:"endLevel" yaml:"endLevel"`
+}
+
+// Levels will return the configured start and end levels, or the defaults if
+// none are configured.
+func (t TOC) Levels() (int, int) {
+	start, end := t.StartLevel, t.EndLevel
+
+	if start <= 0 {
+		start = 2
+	}
+
+	if end <= 0 {
+		end = 3
+	}
+
+	return start, end
+}
+
+// Heading is a heading found in a markdown document.
+type Heading struct {
+	// Level is the level of the heading, from 1 to 6.
+	Level int
+	// ID is the id of the heading element.
+	ID string
+	// Title is the text of the heading.
+	Title string
+}
+
+// Document converts the markdown of a single resource, which may be converted in
+// several pieces, such as the content of paired shortcodes. Heading ids are
+// unique across every piece of a document.
+type Document struct {
+	ids *headingIDs
+}
+
+// NewDocument will return a document with no used heading ids.
+func NewDocument() *Document {
+	return &Document{ids: &headingIDs{values: make(map[string]bool)}}
+}
+
+// Convert will convert a piece of the document to HTML, and return the headings
+// found in it.
+func (d *Document) Convert(data []byte, out io.Writer) ([]Heading, error) {
+	ctx := parser.NewContext(parser.WithIDs(d.ids))
+
+	err := engine.Convert(data, out, parser.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	headings, _ := ctx.Get(headingsKey).([]Heading)
+
+	return headings, nil
+}
+
+// placeholderPattern matches the tokens returned by Placeholder.
+var placeholderPattern = regexp.MustCompile(`ONYXPLACEHOLDER\d+X`)
+
+// Placeholder will return a token that stands in for content inserted into the
+// HTML after conversion, such as the output of a shortcode. Placeholders are left
+// out of heading ids and titles.
+func Placeholder(n int) string {
+	return fmt.Sprintf("ONYXPLACEHOLDER%vX", n)
+}
+
+// withoutPlaceholders will remove every placeholder from s, along with the extra
+// whitespace left behind.
+func withoutPlaceholders(s string) string {
+	return strings.Join(strings.Fields(placeholderPattern.ReplaceAllString(s, " ")), " ")
+}
+
+// headingsKey holds the headings collected by headingTransformer in the parser context.
+var headingsKey = parser.NewContextKey()
+
+// headingTransformer collects the headings of a document, and appends a permalink
+// anchor to each one if anchors are enabled.
+type headingTransformer struct {
+	anchors Anchors
+}
+
+func (t *headingTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var headings []Heading
+
+	source := reader.Source()
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+
+		value, ok := heading.AttributeString("id")
+		if !ok {
+			return ast.WalkSkipChildren, nil
+		}
+
+		id, _ := value.([]byte)
+
+		headings = append(headings, Heading{
+			Level: heading.Level,
+			ID:    string(id),
+			Title: withoutPlaceholders(string(heading.Text(source))),
+		})
+
+		if t.anchors.Enabled {
+			anchor := ast.NewString([]byte(t.anchor(string(id))))
+			anchor.SetCode(true)
+			heading.AppendChild(heading, anchor)
+		}
+
+		return ast.WalkSkipChildren, nil
+	})
+
+	pc.Set(headingsKey, headings)
+}
+
+// anchor will return the HTML of the permalink anchor of a heading. The anchor is
+// hidden from assistive technology, which also leaves it out of plain text such
+// as the summaries of feeds.
+func (t *headingTransformer) anchor(id string) string {
+	label, class := t.anchors.Text, t.anchors.Class
+
+	if label == "" {
+		label = "#"
+	}
+
+	if class == "" {
+		class = "anchor"
+	}
+
+	return fmt.Sprintf(` <a class="%v" href="#%v" aria-hidden="true">%v</a>`,
+		escape(class), escape(id), escape(label))
+}
+
+// escape will escape a string for use in HTML.
+func escape(s string) string {
+	return string(util.EscapeHTML([]byte(s)))
+}
+
+// headingIDs generates the ids of headings, such as "getting-started" for a heading
+// titled "Getting Started". A number is appended to an id that is already used,
+// such as "getting-started-1".
+type headingIDs struct {
+	values map[string]bool
+}
+
+func (s *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	var builder strings.Builder
+
+	dash := false
+	for _, r := range withoutPlaceholders(string(value)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			builder.WriteRune(unicode.ToLower(r))
+			dash = false
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			if !dash {
+				builder.WriteRune('-')
+				dash = true
+			}
+		}
+	}
+
+	result := strings.Trim(builder.String(), "-")
+	if result == "" {
+		result = "heading"
+	}
+
+	id := result
+	for i := 1; s.values[id]; i++ {
+		id = fmt.Sprintf("%v-%v", result, i)
+	}
+
+	s.values[id] = true
+
+	return []byte(id)
+}
+
+func (s *headingIDs) Put(value []byte) {
+	s.values[string(value)] = true
+}
@@ -1,16 +1,197 @@
 package md
 
 import (
+	"fmt"
 	"io"
+	"strings"
 
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
 	_md "github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
 )
 
+// Options controls the extensions and renderer options of the markdown engine.
+// Every option is disabled by default. Every heading is given an id, which is
+// generated from its text or set with an attribute, such as `## Usage {#usage}`.
+type Options struct {
+	// Tables enables GitHub Flavored Markdown tables.
+	Tables bool `json:"tables" yaml:"tables"`
+	// Strikethrough enables `~~deleted~~` text.
+	Strikethrough bool `json:"strikethrough" yaml:"strikethrough"`
+	// Footnotes enables footnote references and definitions.
+	Footnotes bool `json:"footnotes" yaml:"footnotes"`
+	// TaskLists enables `- [x]` list items.
+	TaskLists bool `json:"taskLists" yaml:"taskLists"`
+	// Typographer replaces punctuation such as quotes and dashes with
+	// typographic entities.
+	Typographer bool `json:"typographer" yaml:"typographer"`
+	// DefinitionLists enables PHP Markdown Extra definition lists.
+	DefinitionLists bool `json:"definitionLists" yaml:"definitionLists"`
+	// Unsafe renders raw HTML and potentially dangerous links, which are
+	// otherwise omitted.
+	Unsafe bool `json:"unsafe" yaml:"unsafe"`
+	// HardWraps renders newlines in paragraphs as `<br>`.
+	HardWraps bool `json:"hardWraps" yaml:"hardWraps"`
+	// XHTML renders self closing tags such as `<br />`.
+	XHTML bool `json:"xhtml" yaml:"xhtml"`
+	// Highlight controls syntax highlighting of fenced code blocks.
+	Highlight Highlight `json:"highlight" yaml:"highlight"`
+	// Anchors controls the permalink anchor appended to every heading.
+	Anchors Anchors `json:"anchors" yaml:"anchors"`
+	// TOC controls the levels of the headings found in the table of contents.
+	TOC TOC `json:"toc" yaml:"toc"`
+}
+
+// DefStyle is the chroma style used when highlighting is enabled without a style.
+const DefStyle = "github"
+
+// Highlight controls syntax highlighting of fenced code blocks, which is done with
+// chroma when markdown is converted. A code block may highlight lines or enable
+// line numbers with attributes after its language, such as
+// ```go {hl_lines=[3,"5-6"], linenos=true}.
+type Highlight struct {
+	// Enabled turns on syntax highlighting.
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Style is the name of the chroma style, such as "monokai".
+	Style string `json:"style" yaml:"style"`
+	// Inline writes styles on each element rather than classes, which require the
+	// stylesheet written by the highlight command.
+	Inline bool `json:"inline" yaml:"inline"`
+	// LineNumbers enables line numbers on every code block.
+	LineNumbers bool `json:"lineNumbers" yaml:"lineNumbers"`
+	// LineNumbersInTable places line numbers in a table column, so that they are
+	// not selected along with the code.
+	LineNumbersInTable bool `json:"lineNumbersInTable" yaml:"lineNumbersInTable"`
+	// GuessLanguage highlights code blocks without a language by guessing it.
+	GuessLanguage bool `json:"guessLanguage" yaml:"guessLanguage"`
+}
+
+// StyleName will return the configured style, or DefStyle if none is configured.
+func (h Highlight) StyleName() string {
+	if h.Style != "" {
+		return h.Style
+	}
+
+	return DefStyle
+}
+
+// engine is the markdown engine used by Unmarshal.
+var engine = New(Options{})
+
+// Configure will replace the markdown engine used by Unmarshal with one built
+// from the given options. An error is returned if the highlight style is unknown.
+func Configure(opts Options) error {
+	if opts.Highlight.Enabled {
+		if _, err := Style(opts.Highlight.StyleName()); err != nil {
+			return err
+		}
+	}
+
+	engine = New(opts)
+
+	return nil
+}
+
+// New will create a markdown engine from the given options.
+func New(opts Options) _md.Markdown {
+	var extensions []_md.Extender
+
+	if opts.Tables {
+		extensions = append(extensions, extension.Table)
+	}
+
+	if opts.Strikethrough {
+		extensions = append(extensions, extension.Strikethrough)
+	}
+
+	if opts.Footnotes {
+		extensions = append(extensions, extension.Footnote)
+	}
+
+	if opts.TaskLists {
+		extensions = append(extensions, extension.TaskList)
+	}
+
+	if opts.Typographer {
+		extensions = append(extensions, extension.Typographer)
+	}
+
+	if opts.DefinitionLists {
+		extensions = append(extensions, extension.DefinitionList)
+	}
+
+	if opts.Highlight.Enabled {
+		extensions = append(extensions, highlighting.NewHighlighting(
+			highlighting.WithStyle(opts.Highlight.StyleName()),
+			highlighting.WithGuessLanguage(opts.Highlight.GuessLanguage),
+			highlighting.WithFormatOptions(
+				chromahtml.WithClasses(!opts.Highlight.Inline),
+				chromahtml.WithLineNumbers(opts.Highlight.LineNumbers),
+				chromahtml.LineNumbersInTable(opts.Highlight.LineNumbersInTable),
+			),
+		))
+	}
+
+	var renderOpts []renderer.Option
+
+	if opts.Unsafe {
+		renderOpts = append(renderOpts, html.WithUnsafe())
+	}
+
+	if opts.HardWraps {
+		renderOpts = append(renderOpts, html.WithHardWraps())
+	}
+
+	if opts.XHTML {
+		renderOpts = append(renderOpts, html.WithXHTML())
+	}
+
+	return _md.New(
+		_md.WithExtensions(extensions...),
+		_md.WithParserOptions(
+			parser.WithAutoHeadingID(),
+			parser.WithAttribute(),
+			parser.WithASTTransformers(util.Prioritized(&headingTransformer{anchors: opts.Anchors}, 1000)),
+		),
+		_md.WithRendererOptions(renderOpts...),
+	)
+}
+
+// Unmarshal will convert a markdown document to HTML.
 func Unmarshal(data []byte, out io.Writer) error {
-	err := _md.Convert(data, out)
+	_, err := NewDocument().Convert(data, out)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// Style will return the chroma style with the given name, or an error if no
+// style has the name.
+func Style(name string) (*chroma.Style, error) {
+	style, ok := styles.Registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown highlight style `%v`, expected one of: %v", name, strings.Join(styles.Names(), ", "))
+	}
+
+	return style, nil
+}
+
+// CSS will write the stylesheet of the named chroma style to out, which matches
+// the classes written when highlighting is enabled without inline styles.
+func CSS(name string, out io.Writer) error {
+	style, err := Style(name)
+	if err != nil {
+		return err
+	}
+
+	return chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(out, style)
+}
@@ -0,0 +1,14 @@
+package toml
+
+import (
+	_toml "github.com/BurntSushi/toml"
+)
+
+func Unmarshal(data []byte, out any) error {
+	_, err := _toml.Decode(string(data), out)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -3,8 +3,13 @@ module github.com/jmkng/onyx
 go 1.19
 
 require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/alecthomas/chroma/v2 v2.14.0
 	github.com/jmkng/mute v0.1.3
 	github.com/yuin/goldmark v1.5.3
+	github.com/yuin/goldmark-highlighting/v2 v2.0.0-20230729083705-37449abec8cc
 	golang.org/x/text v0.5.0
 	gopkg.in/yaml.v3 v3.0.1
 )
+
+require github.com/dlclark/regexp2 v1.11.0 // indirect
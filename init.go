@@ -35,6 +35,7 @@ func Init(args []string) error {
 		fmt.Println("> create")
 		fmt.Println("> build")
 		fmt.Println("> serve")
+		fmt.Println("> highlight")
 		fmt.Println("View command information with `onyx <command> [--help|-h]`")
 	}
 
@@ -47,6 +48,7 @@ func Init(args []string) error {
 		routine.NewCreate(),
 		routine.NewBuild(),
 		routine.NewServe(),
+		routine.NewHighlight(),
 	}
 
 	for _, rt := range routines {
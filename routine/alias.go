@@ -0,0 +1,206 @@
+package routine
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jmkng/onyx/config"
+)
+
+const (
+	// Front matter key holding the old links of a resource that redirect to it.
+	AliasesKey = "aliases"
+
+	// Recognized hosts that a redirects file can be written for.
+	HostNetlify = "netlify"
+	HostNginx   = "nginx"
+	HostApache  = "apache"
+)
+
+// redirectFiles maps a host to the name of the redirects file written for it.
+var redirectFiles = map[string]string{
+	HostNetlify: "_redirects",
+	HostNginx:   "redirects.map",
+	HostApache:  ".htaccess",
+}
+
+// redirectPage is the document written at the location of each alias.
+const redirectPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Redirecting to %[1]v</title>
+<link rel="canonical" href="%[2]v">
+<meta name="robots" content="noindex">
+<meta http-equiv="refresh" content="0; url=%[1]v">
+</head>
+<body>
+<p>Redirecting to <a href="%[1]v">%[1]v</a>.</p>
+</body>
+</html>
+`
+
+// alias is an old link that redirects to a resource.
+type alias struct {
+	// from is the old link, relative to the site root.
+	from string
+	// to is the link of the resource.
+	to string
+	// file is the location of the redirect page, relative to the output directory.
+	file string
+}
+
+// redirects will write a redirect page at the location of every alias of the
+// written resources, along with the redirects file of the host named in
+// config.State.Redirects. An error is returned if a redirect page would replace
+// any other file written by the build. The aliases are kept so that serve can
+// answer them with a permanent redirect.
+func (routine *Build) redirects(written []resource) error {
+	var aliases []alias
+
+	for _, res := range written {
+		// every page of a listing holds its front matter, but only the first
+		// page is the target of its aliases
+		if res.paginator != nil && res.paginator.Page > 1 {
+			continue
+		}
+
+		list, err := res.aliases()
+		if err != nil {
+			return err
+		}
+
+		aliases = append(aliases, list...)
+	}
+
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].from < aliases[j].from
+	})
+
+	// written is keyed by absolute path
+	output, err := routine.output()
+	if err != nil {
+		return err
+	}
+
+	lookup := make(map[string]string)
+
+	for _, v := range aliases {
+		dest := filepath.Join(output, v.file)
+
+		if routine.written[dest] {
+			return fmt.Errorf("alias `%v` of `%v` conflicts with an existing file: %v", v.from, v.to, v.file)
+		}
+
+		canonical := v.to
+		if config.State.BaseURL != "" {
+			canonical = absURL(v.to)
+		}
+
+		page := fmt.Sprintf(redirectPage, html.EscapeString(v.to), html.EscapeString(canonical))
+
+		err := routine.write(dest, []byte(page))
+		if err != nil {
+			return err
+		}
+
+		lookup["/"+filepath.ToSlash(v.file)] = v.to
+	}
+
+	routine.mu.Lock()
+	routine.aliases = lookup
+	routine.mu.Unlock()
+
+	host := config.State.Redirects
+	if host == "" {
+		return nil
+	}
+
+	name, ok := redirectFiles[host]
+	if !ok {
+		return fmt.Errorf("unrecognized redirects host in configuration file: %v", host)
+	}
+
+	return routine.write(filepath.Join(output, name), []byte(redirectsFile(host, aliases)))
+}
+
+// redirect will return the link that an alias redirects to, or false if the
+// requested path is not an alias.
+func (routine *Build) redirect(request string) (string, bool) {
+	if strings.HasSuffix(request, "/") || path.Ext(request) == "" {
+		request = path.Join(request, "index.html")
+	}
+
+	routine.mu.RLock()
+	defer routine.mu.RUnlock()
+
+	to, ok := routine.aliases[path.Clean(request)]
+
+	return to, ok
+}
+
+// aliases will return the aliases found in the front matter of a resource, which
+// may be a single string or a list of strings.
+func (res resource) aliases() ([]alias, error) {
+	value, ok := res.data[AliasesKey]
+	if !ok {
+		return nil, nil
+	}
+
+	var links []string
+
+	switch v := value.(type) {
+	case string:
+		links = []string{v}
+	case []any:
+		for _, item := range v {
+			link, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("front matter key `%v` must be a list of strings in resource: %v", AliasesKey, res.path)
+			}
+
+			links = append(links, link)
+		}
+	default:
+		return nil, fmt.Errorf("front matter key `%v` must be a list of strings in resource: %v", AliasesKey, res.path)
+	}
+
+	var result []alias
+
+	for _, link := range links {
+		file := permalinkFile(link)
+
+		from := "/" + strings.TrimSuffix(filepath.ToSlash(file), "index.html")
+
+		result = append(result, alias{
+			from: from,
+			to:   res.link,
+			file: file,
+		})
+	}
+
+	return result, nil
+}
+
+// redirectsFile will return the contents of the redirects file for a host.
+// The nginx file is a list of entries meant to be included in a `map` block.
+func redirectsFile(host string, aliases []alias) string {
+	var builder strings.Builder
+
+	for _, v := range aliases {
+		switch host {
+		case HostNetlify:
+			fmt.Fprintf(&builder, "%v %v 301\n", v.from, v.to)
+		case HostNginx:
+			fmt.Fprintf(&builder, "%v %v;\n", v.from, v.to)
+		case HostApache:
+			fmt.Fprintf(&builder, "Redirect 301 %v %v\n", v.from, v.to)
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,169 @@
+package routine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmkng/onyx/config"
+)
+
+func TestBuildAliases(t *testing.T) {
+	t.Run("redirect pages and redirects file are written", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName:                     "baseURL: https://example.com\nredirects: netlify\n",
+			filepath.Join("routes", "blog", "a.md"): "---\naliases: [/old/a/, /legacy.html]\n---\na",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		page, err := os.ReadFile(filepath.Join(dir, "build", "old", "a", "index.html"))
+		if err != nil {
+			t.Log("expected redirect page for alias")
+			t.FailNow()
+		}
+
+		expected := []string{
+			`<meta http-equiv="refresh" content="0; url=/blog/a/index.html">`,
+			`<link rel="canonical" href="https://example.com/blog/a/index.html">`,
+		}
+
+		for _, v := range expected {
+			if !strings.Contains(string(page), v) {
+				t.Logf("expected redirect page to contain %v", v)
+				t.Fail()
+			}
+		}
+
+		if !exists(filepath.Join(dir, "build", "legacy.html")) {
+			t.Log("expected redirect page for file alias")
+			t.Fail()
+		}
+
+		redirects, _ := os.ReadFile(filepath.Join(dir, "build", "_redirects"))
+		if string(redirects) != "/legacy.html /blog/a/index.html 301\n/old/a/ /blog/a/index.html 301\n" {
+			t.Logf("received redirects file %v", string(redirects))
+			t.Fail()
+		}
+
+		for _, request := range []string{"/old/a/", "/old/a", "/old/a/index.html", "/legacy.html"} {
+			to, ok := routine.redirect(request)
+			if !ok || to != "/blog/a/index.html" {
+				t.Logf("expected %v to redirect, received %v", request, to)
+				t.Fail()
+			}
+		}
+	})
+
+	t.Run("aliases may not replace a resource", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("routes", "index.html"):   "home",
+			filepath.Join("routes", "blog", "a.md"): "---\naliases: /\n---\na",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err == nil {
+			t.Log("expected error for conflicting alias")
+			t.Fail()
+		}
+	})
+
+	t.Run("aliases of a paginated listing redirect to the first page", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("routes", "blog.tmpl"):       "---\npaginate: posts\nperPage: 1\naliases: /old-blog/\n---\n{{ range .Paginator.Items }}{{ .Title }}{{ end }}",
+			filepath.Join("routes", "posts", "one.md"): "---\ntitle: one\ndate: 2022-01-01\n---\none",
+			filepath.Join("routes", "posts", "two.md"): "---\ntitle: two\ndate: 2022-01-02\n---\ntwo",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		to, ok := routine.redirect("/old-blog/")
+		if !ok || to != "/blog/index.html" {
+			t.Logf("expected alias to redirect to the first page, received %v", to)
+			t.Fail()
+		}
+	})
+
+	t.Run("aliases may not replace a resource when the project path is relative", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("routes", "about.md"):     "about",
+			filepath.Join("routes", "blog", "a.md"): "---\naliases: /about/\n---\na",
+		})
+
+		wd, err := os.Getwd()
+		if err == nil {
+			err = os.Chdir(filepath.Dir(dir))
+		}
+
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		defer os.Chdir(wd)
+
+		routine := Build{path: filepath.Base(dir)}
+
+		err = routine.build()
+		if err == nil {
+			t.Log("expected error for conflicting alias")
+			t.Fail()
+		}
+	})
+
+	t.Run("aliases may not replace a static file", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("static", "old.html"):     "static",
+			filepath.Join("routes", "blog", "a.md"): "---\naliases: /static/old.html\n---\na",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err == nil {
+			t.Log("expected error for alias conflicting with static file")
+			t.Fail()
+		}
+	})
+
+	t.Run("serve answers aliases with a permanent redirect", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("routes", "blog", "a.md"): "---\naliases: [/old/a/]\n---\na",
+		})
+
+		builder := &Build{path: dir}
+
+		err := builder.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		serve := &Serve{path: dir, builder: builder}
+
+		recorder := httptest.NewRecorder()
+		serve.handler(recorder, httptest.NewRequest(http.MethodGet, "/old/a/", nil))
+
+		if recorder.Code != http.StatusMovedPermanently || recorder.Header().Get("Location") != "/blog/a/index.html" {
+			t.Logf("expected permanent redirect, received %v %v", recorder.Code, recorder.Header().Get("Location"))
+			t.Fail()
+		}
+	})
+}
@@ -16,7 +16,6 @@ import (
 
 	"github.com/jmkng/onyx/config"
 	"github.com/jmkng/onyx/convert/md"
-	"github.com/jmkng/onyx/convert/yaml"
 	"github.com/jmkng/onyx/track"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -29,6 +28,10 @@ func NewBuild() *Build {
 
 	routine.fs.StringVar(&routine.path, "path", WdOrPanic(), "Path to the project being built.")
 	routine.fs.BoolVar(&routine.verbose, "verbose", false, "Display more detailed information")
+	routine.fs.BoolVar(&routine.watch, "watch", false, "Rebuild the project when a source file changes.")
+	routine.fs.BoolVar(&routine.drafts, "drafts", false, "Include resources marked as drafts.")
+	routine.fs.BoolVar(&routine.future, "future", false, "Include resources with a date in the future.")
+	routine.fs.BoolVar(&routine.clean, "clean", false, "Remove everything in the output directory before building.")
 
 	return routine
 }
@@ -37,6 +40,18 @@ type Build struct {
 	fs      *flag.FlagSet
 	path    string
 	verbose bool
+	watch   bool
+	drafts  bool
+	future  bool
+	clean   bool
+	// written holds the path of every file written during the current build.
+	written map[string]bool
+	// aliases maps the redirect page of each alias to the link it redirects to.
+	aliases map[string]string
+	// outputDir holds the absolute path to the output directory of the current
+	// build, which serve reads while config.State may be replaced by a rebuild.
+	outputDir string
+	mu        sync.RWMutex
 }
 
 func (routine *Build) Name() string {
@@ -48,11 +63,61 @@ func (routine *Build) Parse(args []string) error {
 }
 
 func (routine *Build) Execute() error {
+	if !routine.watch {
+		return routine.build()
+	}
+
+	rebuild := func() {
+		err := routine.build()
+		track.Flush()
+
+		if err != nil {
+			fmt.Printf("build failed\n%v\n", err)
+			return
+		}
+
+		fmt.Println("build complete")
+	}
+
+	rebuild()
+
+	fmt.Printf("watching for changes in %v\n", routine.path)
+
+	newWatcher(routine.path).run(nil, func(changed []string) {
+		fmt.Printf("detected %v changed file(s), rebuilding\n", len(changed))
+		rebuild()
+	})
+
+	return nil
+}
+
+// build will render every resource in the project and copy static files to the
+// output directory. If a fatal error is encountered, build returns early with
+// the error.
+func (routine *Build) build() error {
 	err := Setup(routine.path)
 	if err != nil {
 		return err
 	}
 
+	routine.written = make(map[string]bool)
+
+	output, err := routine.output()
+	if err != nil {
+		return err
+	}
+
+	routine.mu.Lock()
+	routine.outputDir = output
+	routine.mu.Unlock()
+
+	if routine.clean {
+		err = routine.wipe()
+		if err != nil {
+			return err
+		}
+	}
+
 	routes := filepath.Join(routine.path, "routes")
 
 	_, err = os.Stat(routes)
@@ -60,9 +125,8 @@ func (routine *Build) Execute() error {
 		return fmt.Errorf("project has no routes: %v", filepath.Base(routine.path))
 	}
 
-	resourceChan := make(chan resourceEvent)
+	var paths []string
 
-	resourceCt := 0
 	err = filepath.WalkDir(routes, func(path string, d fs.DirEntry, err error) error {
 		ignored := isIgnored(path)
 
@@ -91,21 +155,26 @@ func (routine *Build) Execute() error {
 			return nil
 		}
 
-		resourceCt++
+		paths = append(paths, path)
 
-		go func() {
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to walk directory: %v", filepath.Base(routine.path))
+	}
+
+	resourceCt := len(paths)
+	resourceChan := make(chan resourceEvent, resourceCt)
+
+	for _, path := range paths {
+		go func(path string) {
 			res, err := newResource(routine.path, path, routine.verbose)
 
 			resourceChan <- resourceEvent{
 				res: res,
 				err: err,
 			}
-		}()
-
-		return nil
-	})
-	if err != nil {
-		return fmt.Errorf("unable to walk directory: %v", filepath.Base(routine.path))
+		}(path)
 	}
 
 	var render sync.WaitGroup
@@ -114,12 +183,34 @@ func (routine *Build) Execute() error {
 	var injectable injectable
 	var renderable []resource
 
+	now := time.Now()
+
+	// permalinks make it possible for two resources to share a destination
+	destinations := make(map[string]string)
+
 	for i := 0; i < resourceCt; i++ {
 		event := <-resourceChan
 		if event.err != nil {
 			return event.err
 		}
 
+		reason, err := routine.withhold(event.res, now)
+		if err != nil {
+			return err
+		}
+
+		if reason != "" {
+			track.Log(fmt.Sprintf("held back %v: %v", event.res.path, reason))
+
+			render.Done()
+			continue
+		}
+
+		err = claim(destinations, event.res)
+		if err != nil {
+			return err
+		}
+
 		renderable = append(renderable, event.res)
 
 		go func() {
@@ -130,6 +221,21 @@ func (routine *Build) Execute() error {
 
 	render.Wait()
 
+	data, err := loadData(routine.path)
+	if err != nil {
+		return err
+	}
+
+	if injectable.Data == nil {
+		injectable.Data = make(map[string]any)
+	}
+
+	if _, ok := injectable.Data["Data"]; ok {
+		return errors.New("group `data` conflicts with the data directory, please rename the group")
+	}
+
+	injectable.Data["Data"] = data
+
 	for _, v := range injectable.Data {
 		group, ok := v.([]map[string]any)
 
@@ -138,9 +244,9 @@ func (routine *Build) Execute() error {
 		}
 
 		for _, v := range group {
-			pathString, ok := v["***PATH"].(string)
+			pathString, ok := v["***Path"].(string)
 			if !ok {
-				panic("failed to assert ***PATH as string")
+				panic("failed to assert ***Path as string")
 			}
 
 			_, dateExists := v["Date"]
@@ -160,7 +266,7 @@ func (routine *Build) Execute() error {
 				return errDate
 			}
 
-			date, err := time.Parse(config.DateFmt, asStr)
+			date, err := parseDate(asStr)
 			if err != nil {
 				return errDate
 			}
@@ -169,165 +275,117 @@ func (routine *Build) Execute() error {
 		}
 
 		sort.Slice(group, func(i, j int) bool {
-			iDate, ok := group[i]["***DATE"].(time.Time)
-			if !ok {
-				panic("failed to assert ***DATE as time.Time")
-			}
-
-			jDate, ok := group[j]["***DATE"].(time.Time)
-			if !ok {
-				panic("failed to assert ***DATE as time.Time")
-			}
+			// resources missing a date hold the zero value and sort last
+			iDate, _ := group[i]["***DATE"].(time.Time)
+			jDate, _ := group[j]["***DATE"].(time.Time)
 
 			return iDate.After(jDate)
 		})
 	}
 
-	renderedChan := make(chan resourceEvent)
-	renderedCt := 0
-
-	for i := range renderable {
-		renderedCt++
+	if _, ok := injectable.Data["Taxonomies"]; ok {
+		return errors.New("group `taxonomies` conflicts with the taxonomy index, please rename the group")
+	}
 
-		toTemplates := filepath.Join(routine.path, "templates")
-		toBase := filepath.Join(toTemplates, "base.tmpl")
+	taxonomies, generated, err := routine.classify(renderable)
+	if err != nil {
+		return err
+	}
 
-		_, err = os.Stat(toBase)
+	for _, res := range generated {
+		err = claim(destinations, res)
 		if err != nil {
-			return fmt.Errorf("missing base template `base.tmpl` in %v", toTemplates)
+			return err
 		}
+	}
 
-		var base []string
-		err = filepath.WalkDir(toTemplates, func(path string, d fs.DirEntry, err error) error {
-			if d.IsDir() {
-				return nil
-			}
-
-			if !d.IsDir() && d.Type().IsRegular() {
-				pathBase := filepath.Base(path)
-				segments := strings.Split(pathBase, ".")
-
-				if len(segments) < 2 {
-					return nil
-				}
-
-				prefix := strings.HasPrefix(segments[0], "base_")
+	injectable.Data["Taxonomies"] = taxonomies
+	renderable = append(renderable, generated...)
 
-				if !prefix && segments[1] == "tmpl" {
-					return nil
-				}
+	renderable, err = routine.paginate(renderable, injectable.Data)
+	if err != nil {
+		return err
+	}
 
-				base = append(base, path)
-			}
+	// the first page of a listing was claimed when it was read
+	for _, res := range renderable {
+		if res.paginator == nil || res.paginator.Page == 1 {
+			continue
+		}
 
-			return nil
-		})
+		err = claim(destinations, res)
 		if err != nil {
-			return fmt.Errorf("unable to walk directory: %v", filepath.Base(toTemplates))
+			return err
 		}
+	}
 
-		caser := cases.Title(language.English)
-
-		templates := []string{toBase}
-		go func(res resource, out chan resourceEvent) {
-			var request string
-
-			if res.template != "" {
-				request = filepath.Join(toTemplates, res.template)
-			}
-
-			if request != "" {
-				_, err := os.Stat(request)
-				if err != nil {
-					renderedChan <- resourceEvent{
-						res: res,
-						err: fmt.Errorf("unable to locate template `%v` requested by: %v", res.template, filepath.Base(res.path)),
-					}
-
-					return
-				}
-
-				templates = append(templates, request)
-			}
-
-			if res.ext == ".tmpl" {
-				var buf bytes.Buffer
-				prerender := template.New("prerender")
-				prerender.Parse(string(res.transformed))
-				prerender.Execute(&buf, injectable.Data)
-				res.transformed = template.HTML(buf.String())
-			}
-
-			context := make(map[string]any)
-
-			context["Content"] = res.transformed
-
-			for k, v := range injectable.Data {
-				context[k] = v
-			}
-
-			for k, v := range res.data {
-				title := caser.String(k)
-				context[title] = v
-			}
+	toTemplates := filepath.Join(routine.path, "templates")
 
-			tmpl, err := template.ParseFiles(templates...)
-			if err != nil {
-				wrapped := fmt.Errorf("failed to parse templates for resource: %v\n%v", res.path, err)
+	_, err = os.Stat(filepath.Join(toTemplates, "base.tmpl"))
+	if err != nil {
+		return fmt.Errorf("missing base template `base.tmpl` in %v", toTemplates)
+	}
 
-				renderedChan <- resourceEvent{
-					res: res,
-					err: wrapped,
-				}
+	partials, err := routine.partials()
+	if err != nil {
+		return err
+	}
 
-				return
-			}
+	renderedChan := make(chan resourceEvent, len(renderable))
+	renderedCt := 0
 
-			var buf bytes.Buffer
-			err = tmpl.Execute(&buf, context)
-			if err != nil {
-				renderedChan <- resourceEvent{
-					res: resource{},
-					err: fmt.Errorf("encountered a problem while executing template\n%v", err.Error()),
-				}
-				return
-			}
+	for i := range renderable {
+		renderedCt++
 
-			res.rendered = buf.String()
+		go func(res resource) {
+			rendered, err := routine.render(res, injectable.Data, partials)
 
 			renderedChan <- resourceEvent{
-				res: res,
-				err: nil,
+				res: rendered,
+				err: err,
 			}
-		}(renderable[i], renderedChan)
+		}(renderable[i])
 	}
 
+	var written []resource
+
 	for i := 0; i < renderedCt; i++ {
 		event := <-renderedChan
 		if event.err != nil {
 			return event.err
 		}
 
-		parent := filepath.Dir(event.res.destination)
-
-		_, err = os.Stat(parent)
+		err = routine.write(event.res.destination, []byte(event.res.rendered))
 		if err != nil {
-			err = os.MkdirAll(parent, DefDirPerm)
-			if err != nil {
-				return fmt.Errorf("unable to create directory: %v", parent)
-			}
+			return err
 		}
 
-		err = os.WriteFile(event.res.destination, []byte(event.res.rendered), DefFilePerm)
-		if err != nil {
-			return fmt.Errorf("unable to write file: %v", event.res.destination)
-		}
+		written = append(written, event.res)
+	}
+
+	err = routine.feeds(injectable.Data)
+	if err != nil {
+		return err
+	}
+
+	err = routine.sitemap(written)
+	if err != nil {
+		return err
 	}
 
 	var static []string
 
 	toStatic := filepath.Join(routine.path, "static")
 	err = filepath.WalkDir(toStatic, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// a project is not required to have static files
+			if path == toStatic && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
 		ignored := isIgnored(path)
 
 		if ignored != nil {
@@ -360,43 +418,154 @@ func (routine *Build) Execute() error {
 			return fmt.Errorf("unable to determine output path for static file: %v\n%v", filepath.Base(v), err.Error())
 		}
 
-		parent := filepath.Dir(dest)
+		bytes, err := os.ReadFile(v)
+		if err != nil {
+			return fmt.Errorf("unable to read file: %v", v)
+		}
 
-		err = os.MkdirAll(parent, DefDirPerm)
+		err = routine.write(dest, bytes)
 		if err != nil {
-			return fmt.Errorf("unable to create directory: %v", parent)
+			return err
 		}
+	}
 
-		bytes, err := os.ReadFile(v)
+	// aliases are written last so that they are checked against every other file
+	err = routine.redirects(written)
+	if err != nil {
+		return err
+	}
+
+	return routine.prune()
+}
+
+// render will execute the templates requested by a resource, along with the base
+// template, and return the resource with the result stored in resource.rendered.
+// The shared map holds data available to every resource, such as groups, and
+// partials holds the templates available to every resource.
+func (routine *Build) render(res resource, shared map[string]any, partials *partialSet) (resource, error) {
+	caser := cases.Title(language.English)
+
+	templates, err := routine.layouts(res.template)
+	if err != nil {
+		return res, fmt.Errorf("%v\nrequested by: %v", err, filepath.Base(res.path))
+	}
+
+	if res.ext == ".tmpl" {
+		prerenderContext := make(map[string]any)
+
+		for k, v := range shared {
+			prerenderContext[k] = v
+		}
+
+		if res.paginator != nil {
+			prerenderContext["Paginator"] = res.paginator
+		}
+
+		set, err := partials.with(nil)
 		if err != nil {
-			return fmt.Errorf("unable to read file: %v", v)
+			return res, err
+		}
+
+		var buf bytes.Buffer
+		prerender, err := set.New("prerender").Parse(string(res.transformed))
+		if err == nil {
+			err = prerender.Execute(&buf, prerenderContext)
 		}
 
-		err = os.WriteFile(dest, bytes, DefFilePerm)
 		if err != nil {
-			return fmt.Errorf("unable to write to destination: %v", dest)
+			return res, templateError(fmt.Errorf("encountered a problem while executing route\n%v", err), partials.files(), res.path, res.offset)
 		}
+
+		res.transformed = template.HTML(buf.String())
+	}
+
+	context := make(map[string]any)
+
+	context["Content"] = res.transformed
+	context["TOC"] = res.toc
+
+	for k, v := range shared {
+		context[k] = v
+	}
+
+	for k, v := range res.data {
+		title := caser.String(k)
+		context[title] = v
+	}
+
+	if res.paginator != nil {
+		context["Paginator"] = res.paginator
+	}
+
+	for k, v := range res.context {
+		context[k] = v
+	}
+
+	layouts, err := template.New(filepath.Base(templates[0])).Funcs(funcs()).ParseFiles(templates...)
+	if err != nil {
+		wrapped := fmt.Errorf("failed to parse layout chain %v for resource: %v\n%v", describeChain(templates), res.path, err)
+
+		return res, templateError(wrapped, templates, res.path, 0)
+	}
+
+	tmpl, err := partials.with(layouts)
+	if err != nil {
+		return res, fmt.Errorf("failed to parse templates for resource: %v\n%v", res.path, err)
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.ExecuteTemplate(&buf, filepath.Base(templates[0]), context)
+	if err != nil {
+		files := append(templates, partials.files()...)
+
+		return resource{}, templateError(fmt.Errorf("encountered a problem while executing layout chain %v\n%v", describeChain(templates), err.Error()), files, res.path, 0)
+	}
+
+	res.rendered = buf.String()
+
+	return res, nil
+}
+
+// claim will record the destination of a resource, which may be read from a file
+// or generated by the build. An error is returned if another resource already
+// has the destination.
+func claim(destinations map[string]string, res resource) error {
+	if other, ok := destinations[res.destination]; ok {
+		return fmt.Errorf("resources share the same link `%v`: %v, %v", res.link, other, res.path)
 	}
 
+	destinations[res.destination] = res.path
+
 	return nil
 }
 
-// pull will find data within a file and extract it, returning the data and
-// content as separate strings. An error is returned if no data exists in the file,
-// or the data is malformed in some way.
-func pull(data string) (string, string, error) {
-	if data[0:3] != "---" {
-		return "", "", errors.New("no data in file")
+// write will write data to the file at dest, creating any missing parent
+// directories. The file is recorded so that it survives routine.prune.
+func (routine *Build) write(dest string, data []byte) error {
+	parent := filepath.Dir(dest)
+
+	_, err := os.Stat(parent)
+	if err != nil {
+		err = os.MkdirAll(parent, DefDirPerm)
+		if err != nil {
+			return fmt.Errorf("unable to create directory: %v", parent)
+		}
+	}
+
+	err = os.WriteFile(dest, data, DefFilePerm)
+	if err != nil {
+		return fmt.Errorf("unable to write file: %v", dest)
 	}
 
-	firstEnd := 3
-	secondStart := strings.Index(data[3:], "---")
-	secondEnd := secondStart + 3
+	// prune compares against absolute paths, and the project path may be relative
+	abs, err := filepath.Abs(dest)
+	if err != nil {
+		return fmt.Errorf("unable to determine absolute path: %v", dest)
+	}
 
-	head := data[firstEnd:secondEnd]
-	body := data[(secondEnd + 3):]
+	routine.written[abs] = true
 
-	return head, body, nil
+	return nil
 }
 
 // diff will determine the difference between two paths, returning the relative
@@ -501,6 +670,11 @@ func out(root, path string) (string, error) {
 				return "", fmt.Errorf("resource is missing a valid extension: %v", path)
 			}
 
+			// hosts expect the not found page at the root of the site
+			if len(segments) == 2 && split[0] == NotFoundName {
+				return filepath.Join(root, output, NotFoundName+".html"), nil
+			}
+
 			joined := filepath.Join(allBetween...)
 			return filepath.Join(root, output, joined, split[0], "index.html"), nil
 		}
@@ -550,31 +724,6 @@ func isUnrecognized(path string) bool {
 	}
 }
 
-// isComplex will return true if the given string begins with a recognized delimiter
-// to indicate that the file contains some data that needs to be extracted.
-func isComplex(data string) bool {
-	partitions := strings.Split(
-		data,
-		"\n",
-	)
-
-	found := 0
-
-	for _, v := range partitions {
-		if found == 2 {
-			break
-		}
-
-		v = strings.ReplaceAll(v, "\t", "")
-
-		if v == "---" {
-			found++
-		}
-	}
-
-	return found == 2
-}
-
 // injectable is a data structure used to hold the key/value pairs from all
 // resources in a project.
 type injectable struct {
@@ -591,6 +740,29 @@ func (i *injectable) absorb(res resource) {
 
 	caser := cases.Title(language.English)
 
+	member := res.member()
+
+	groupTitle := caser.String(res.group)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.Data == nil {
+		i.Data = make(map[string]any)
+	}
+
+	if _, ok := i.Data[groupTitle]; !ok {
+		i.Data[groupTitle] = []map[string]any{}
+	}
+
+	i.Data[groupTitle] = append(i.Data[groupTitle].([]map[string]any), member)
+}
+
+// member will return the data of a resource as it is presented to templates that
+// list the resource, such as the members of a group.
+func (res resource) member() map[string]any {
+	caser := cases.Title(language.English)
+
 	member := make(map[string]any)
 
 	// hidden keys
@@ -600,6 +772,7 @@ func (i *injectable) absorb(res resource) {
 
 	member["***Path"] = res.path
 	member["Content"] = res.transformed
+	member["TOC"] = res.toc
 	member["Date"] = res.date
 	member["Link"] = res.link
 
@@ -608,20 +781,7 @@ func (i *injectable) absorb(res resource) {
 		member[keyTitle] = v
 	}
 
-	groupTitle := caser.String(res.group)
-
-	i.mu.Lock()
-	defer i.mu.Unlock()
-
-	if i.Data == nil {
-		i.Data = make(map[string]any)
-	}
-
-	if _, ok := i.Data[groupTitle]; !ok {
-		i.Data[groupTitle] = []map[string]any{}
-	}
-
-	i.Data[groupTitle] = append(i.Data[groupTitle].([]map[string]any), member)
+	return member
 }
 
 // newResource creates and initializes a new Resource from the file at filePath.
@@ -651,21 +811,6 @@ func newResource(root, file string, verbose bool) (resource, error) {
 		return resource{}, fmt.Errorf("unable to determine relative path to resource: %v", file)
 	}
 
-	var output string
-
-	if config.State.Output != "" {
-		output = config.State.Output
-	} else {
-		output = "build"
-	}
-
-	link, err := diff(filepath.Join(root, output), res.destination)
-	if err != nil {
-		return resource{}, err
-	}
-
-	res.link = ("/" + link)
-
 	sep := string(filepath.Separator)
 	segments := strings.Split(rel, sep)
 
@@ -675,28 +820,35 @@ func newResource(root, file string, verbose bool) (resource, error) {
 		res.group = group
 	}
 
-	rawData := ""
-	rawBody := asStr
+	matter, err := split(file, asStr)
+	if err != nil {
+		return resource{}, err
+	}
 
-	complex := isComplex(asStr)
+	res.offset = matter.bodyLine - 1
 
-	if complex {
-		rawData, rawBody, err = pull(asStr)
+	if matter.format != "" {
+		res.data, err = matter.unmarshal(file)
 		if err != nil {
-			return resource{}, fmt.Errorf("unable to pull file: %v", file)
+			return resource{}, err
 		}
 
-		// TODO: (FEATURE) YAML is assumed, maybe allow JSON or TOML too.
-		yaml.Unmarshal(
-			[]byte(rawData), &res.data,
-		)
+		if value, ok := res.data["template"]; ok {
+			template, ok := value.(string)
+			if !ok {
+				return resource{}, fmt.Errorf("front matter key `template` must be a string in file: %v", file)
+			}
 
-		if template, ok := res.data["template"]; ok {
 			res.template = template
 			delete(res.data, "template")
 		}
 
-		if date, ok := res.data["date"]; ok {
+		if value, ok := res.data["date"]; ok {
+			date, err := dateString(value)
+			if err != nil {
+				return resource{}, fmt.Errorf("invalid date in resource: %v\n%v", file, err)
+			}
+
 			res.date = date
 			delete(res.data, "date")
 		}
@@ -706,14 +858,26 @@ func newResource(root, file string, verbose bool) (resource, error) {
 		}
 	}
 
+	if res.template == "" {
+		res.template = convention(root, res)
+	}
+
+	link, err := permalink(root, res)
+	if err != nil {
+		return resource{}, err
+	}
+
+	res.destination = filepath.Join(root, Output(), link)
+	res.link = "/" + filepath.ToSlash(link)
+
 	var convertedBody string
 	switch ext {
 	case ".md":
-		var buf bytes.Buffer
-		err = md.Unmarshal([]byte(rawBody), &buf)
-		convertedBody = buf.String()
+		var headings []md.Heading
+		convertedBody, headings, err = markdown(root, file, matter.body, matter.bodyLine, res.member())
+		res.toc = newTOC(headings)
 	case ".html", ".tmpl":
-		convertedBody = rawBody
+		convertedBody = matter.body
 	default:
 		panic("converted unexpected file type")
 	}
@@ -739,7 +903,18 @@ type resource struct {
 	group       string
 	template    string
 	date        string
-	data        map[string]string
+	data        map[string]any
+	// offset is the number of lines before the content of the file begins,
+	// used to report accurate lines for problems in the content.
+	offset int
+	// paginator holds the page of a group that the resource lists, if the
+	// resource requested pagination.
+	paginator *Paginator
+	// context holds additional values for the template context of resources
+	// that are generated by the build instead of read from a file.
+	context map[string]any
+	// toc holds the table of contents of a markdown resource.
+	toc TOC
 }
 
 // resourceEvent is a struct passed through channels that may contain a resource.
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/jmkng/onyx/config"
@@ -82,129 +83,375 @@ func TestIsUnknown(t *testing.T) {
 	})
 }
 
-func TestIsComplex(t *testing.T) {
-	t.Run("string with valid delimiters '---' and tabs '\t' is cleaned, and returns true", func(t *testing.T) {
-		mock := `---
-		title: test
-		---`
+func TestOut(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+
+	t.Run("`index` and `tmpl` extensions on index files return the same", func(t *testing.T) {
+		tmpl := filepath.Join("routes", "index.tmpl")
+		html := filepath.Join("routes", "index.html")
+
+		paths := []string{
+			tmpl,
+			html,
+		}
+
+		expected := filepath.Join(wd, "build", "index.html")
+
+		for _, v := range paths {
+			path, err := out(wd, v)
+			if err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+
+			result := path
+			if result != expected {
+				t.Logf("expected %v, got %v", expected, result)
+				t.Fail()
+			}
+		}
+	})
+
+	t.Run("relative path to index file", func(t *testing.T) {
+		mock := filepath.Join("routes", "index.tmpl")
 
-		complex := isComplex(mock)
+		path, err := out(wd, mock)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
 
-		if !complex {
+		expected := filepath.Join(wd, "build", "index.html")
+		if path != expected {
+			t.Errorf("invalid result for relative path to index file `%v`\nreceived: %v", mock, path)
 			t.Fail()
 		}
 	})
 
-	t.Run("string with valid delimiters '---' returns true", func(t *testing.T) {
-		mock := "---\ntitle: test\n---"
+	t.Run("relative path to group member", func(t *testing.T) {
+		mock := filepath.Join("routes", "posts", "post-one.md")
 
-		complex := isComplex(mock)
+		path, err := out(wd, mock)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
 
-		if !complex {
+		expected := filepath.Join(wd, "build", "posts", "post-one", "index.html")
+		if path != expected {
+			t.Errorf("gave `%v` received `%v`", mock, path)
 			t.Fail()
 		}
 	})
 
-	t.Run("one too few delimiters '--' returns false", func(t *testing.T) {
-		mock := `--\ntitle: test\n---`
+	t.Run("not found page is placed at the root", func(t *testing.T) {
+		for _, mock := range []string{filepath.Join("routes", "404.md"), filepath.Join("routes", "404.tmpl")} {
+			path, err := out(wd, mock)
+			if err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+
+			expected := filepath.Join(wd, "build", "404.html")
+			if path != expected {
+				t.Errorf("gave `%v` received `%v`", mock, path)
+				t.Fail()
+			}
+		}
+	})
+}
+
+// project will create a temporary project containing the given files, along with
+// a configuration file and base template if they are not given.
+func project(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir, err := config.CreateTemp(t, "")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defaults := map[string]string{
+		config.YamlLongName:                     "",
+		filepath.Join("templates", "base.tmpl"): "{{ .Content }}",
+	}
+
+	for path, content := range defaults {
+		if _, ok := files[path]; !ok {
+			files[path] = content
+		}
+	}
+
+	for path, content := range files {
+		full := filepath.Join(dir, path)
 
-		complex := isComplex(mock)
+		err := os.MkdirAll(filepath.Dir(full), 0755)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		err = os.WriteFile(full, []byte(content), 0644)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+	}
+
+	return dir
+}
 
-		if complex {
+// exists will return true if the file at path exists.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+
+	return err == nil
+}
+
+func TestBuildDrafts(t *testing.T) {
+	files := func() map[string]string {
+		return map[string]string{
+			filepath.Join("routes", "index.tmpl"):      "{{ range .Posts }}{{ .Title }};{{ end }}",
+			filepath.Join("routes", "posts", "one.md"): "---\ntitle: one\n---\none",
+			filepath.Join("routes", "posts", "two.md"): "---\ntitle: two\ndraft: true\n---\ntwo",
+		}
+	}
+
+	t.Run("drafts are excluded by default", func(t *testing.T) {
+		dir := project(t, files())
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if exists(filepath.Join(dir, "build", "posts", "two", "index.html")) {
+			t.Log("expected draft to be excluded from output")
+			t.Fail()
+		}
+
+		index, _ := os.ReadFile(filepath.Join(dir, "build", "index.html"))
+		if string(index) != "one;" {
+			t.Logf("expected draft to be excluded from group, received %v", string(index))
 			t.Fail()
 		}
 	})
 
-	t.Run("one too many delimiters '----' is not complex", func(t *testing.T) {
-		mock := `----
-		title: test
-		---`
+	t.Run("drafts are included with the drafts flag", func(t *testing.T) {
+		dir := project(t, files())
+
+		routine := Build{path: dir, drafts: true}
 
-		complex := isComplex(mock)
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
 
-		if complex {
+		if !exists(filepath.Join(dir, "build", "posts", "two", "index.html")) {
+			t.Log("expected draft to be included in output")
 			t.Fail()
 		}
 	})
 }
 
-func TestPull(t *testing.T) {
-	t.Run("YAML is recognized and extracted", func(t *testing.T) {
-		mock := "---\ntitle: test\nauthor: test\n---body"
+func TestBuildMarkdownOptions(t *testing.T) {
+	post := "| a |\n| - |\n| b |\n\n~~old~~ <span>raw</span>\n\n- [x] done\n"
 
-		head, body, err := pull(mock)
+	t.Run("extensions are disabled by default", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("routes", "post.md"): post,
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
 		if err != nil {
-			t.Logf("pull returned err: %v", err)
+			t.Log(err)
 			t.FailNow()
 		}
 
-		if head != "\ntitle: test\nauthor: test\n" || body != "body" {
-			t.Logf("body/head are unexpected values\nhead: %v\nbody: %v", head, body)
+		result, _ := os.ReadFile(filepath.Join(dir, "build", "post", "index.html"))
+		if strings.Contains(string(result), "<table>") || !strings.Contains(string(result), "raw HTML omitted") {
+			t.Logf("received %v", string(result))
 			t.Fail()
 		}
 	})
+
+	t.Run("extensions and renderer options are configurable", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName:                "markdown:\n  tables: true\n  strikethrough: true\n  taskLists: true\n  unsafe: true\n  xhtml: true\n",
+			filepath.Join("routes", "post.md"): post,
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		result, _ := os.ReadFile(filepath.Join(dir, "build", "post", "index.html"))
+
+		expected := []string{
+			"<table>",
+			"<del>old</del>",
+			"<span>raw</span>",
+			`<input checked="" disabled="" type="checkbox" />`,
+		}
+
+		for _, v := range expected {
+			if !strings.Contains(string(result), v) {
+				t.Logf("expected %v, received %v", v, string(result))
+				t.Fail()
+			}
+		}
+	})
 }
 
-func TestOut(t *testing.T) {
-	wd, err := os.Getwd()
-	if err != nil {
-		panic(err)
-	}
+func TestBuildHighlight(t *testing.T) {
+	post := "```go {hl_lines=[2]}\npackage main\nfunc main() {}\n```\n"
 
-	t.Run("`index` and `tmpl` extensions on index files return the same", func(t *testing.T) {
-		tmpl := filepath.Join("routes", "index.tmpl")
-		html := filepath.Join("routes", "index.html")
+	t.Run("code blocks are highlighted with classes", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName:                "markdown:\n  highlight:\n    enabled: true\n    lineNumbers: true\n",
+			filepath.Join("routes", "post.md"): post,
+		})
 
-		paths := []string{
-			tmpl,
-			html,
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
 		}
 
-		expected := filepath.Join(wd, "build", "index.html")
+		result, _ := os.ReadFile(filepath.Join(dir, "build", "post", "index.html"))
 
-		for _, v := range paths {
-			path, err := out(wd, v)
-			if err != nil {
-				t.Log(err)
-				t.FailNow()
+		expected := []string{
+			`class="chroma"`,
+			`<span class="kn">package</span>`,
+			`class="line hl"`,
+			`class="ln"`,
+		}
+
+		for _, v := range expected {
+			if !strings.Contains(string(result), v) {
+				t.Logf("expected %v, received %v", v, string(result))
+				t.Fail()
 			}
+		}
+	})
 
-			result := path
-			if result != expected {
-				t.Logf("expected %v, got %v", expected, result)
+	t.Run("unknown styles are rejected", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName:                "markdown:\n  highlight:\n    enabled: true\n    style: missing\n",
+			filepath.Join("routes", "post.md"): post,
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err == nil {
+			t.Log("expected error for unknown style")
+			t.Fail()
+		}
+	})
+}
+
+func TestBuildHeadings(t *testing.T) {
+	post := "# Guide\n\n## Setup\n\n### Setup\n\n## Usage {#how-to}\n"
+
+	t.Run("headings have unique ids and the table of contents is available", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("templates", "base.tmpl"): "{{ .TOC.HTML }}|{{ range .TOC.Headings }}{{ .ID }};{{ end }}|{{ .Content }}",
+			filepath.Join("routes", "post.md"):      post,
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		result, _ := os.ReadFile(filepath.Join(dir, "build", "post", "index.html"))
+
+		expected := []string{
+			`<h1 id="guide">Guide</h1>`,
+			`<h2 id="setup">Setup</h2>`,
+			`<h3 id="setup-1">Setup</h3>`,
+			`<h2 id="how-to">Usage</h2>`,
+			`<nav class="toc"><ul><li><a href="#setup">Setup</a><ul><li><a href="#setup-1">Setup</a></li></ul></li>`,
+			`|setup;how-to;|`,
+		}
+
+		for _, v := range expected {
+			if !strings.Contains(string(result), v) {
+				t.Logf("expected %v, received %v", v, string(result))
 				t.Fail()
 			}
 		}
 	})
 
-	t.Run("relative path to index file", func(t *testing.T) {
-		mock := filepath.Join("routes", "index.tmpl")
+	t.Run("shortcodes are left out of ids and titles", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("templates", "base.tmpl"):               "{{ .TOC.HTML }}|{{ .Content }}",
+			filepath.Join("templates", "shortcodes", "icon.tmpl"): `<i class="icon"></i>`,
+			filepath.Join("routes", "post.md"):                    "## Watch {{< icon >}} now\n",
+		})
 
-		path, err := out(wd, mock)
+		routine := Build{path: dir}
+
+		err := routine.build()
 		if err != nil {
 			t.Log(err)
 			t.FailNow()
 		}
 
-		expected := filepath.Join(wd, "build", "index.html")
-		if path != expected {
-			t.Errorf("invalid result for relative path to index file `%v`\nreceived: %v", mock, path)
-			t.Fail()
+		result, _ := os.ReadFile(filepath.Join(dir, "build", "post", "index.html"))
+
+		expected := []string{
+			`<a href="#watch-now">Watch now</a>`,
+			`<h2 id="watch-now">Watch <i class="icon"></i> now</h2>`,
+		}
+
+		for _, v := range expected {
+			if !strings.Contains(string(result), v) {
+				t.Logf("expected %v, received %v", v, string(result))
+				t.Fail()
+			}
 		}
 	})
 
-	t.Run("relative path to group member", func(t *testing.T) {
-		mock := filepath.Join("routes", "posts", "post-one.md")
+	t.Run("anchors are configurable", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName:                "markdown:\n  anchors:\n    enabled: true\n    text: ¶\n",
+			filepath.Join("routes", "post.md"): post,
+		})
 
-		path, err := out(wd, mock)
+		routine := Build{path: dir}
+
+		err := routine.build()
 		if err != nil {
 			t.Log(err)
 			t.FailNow()
 		}
 
-		expected := filepath.Join(wd, "build", "posts", "post-one", "index.html")
-		if path != expected {
-			t.Errorf("gave `%v` received `%v`", mock, path)
+		result, _ := os.ReadFile(filepath.Join(dir, "build", "post", "index.html"))
+
+		expected := `<h2 id="how-to">Usage <a class="anchor" href="#how-to" aria-hidden="true">¶</a></h2>`
+		if !strings.Contains(string(result), expected) {
+			t.Logf("expected %v, received %v", expected, string(result))
 			t.Fail()
 		}
 	})
@@ -0,0 +1,171 @@
+package routine
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jmkng/onyx/config"
+	"github.com/jmkng/onyx/track"
+)
+
+// wipe will remove everything in the output directory.
+func (routine *Build) wipe() error {
+	output, err := routine.output()
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(output)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("unable to read directory: %v", output)
+	}
+
+	for _, entry := range entries {
+		err = os.RemoveAll(filepath.Join(output, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("unable to remove from output directory: %v", entry.Name())
+		}
+	}
+
+	return nil
+}
+
+// prune will remove every file from the output directory that was not written
+// during the current build, such as the output of a route that has since been
+// deleted. Files matching a pattern in config.State.Preserve are kept, and any
+// directories left empty are removed.
+func (routine *Build) prune() error {
+	output, err := routine.output()
+	if err != nil {
+		return err
+	}
+
+	var dirs []string
+
+	err = filepath.WalkDir(output, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if file == output && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		rel, err := diff(output, file)
+		if err != nil || file == output {
+			return nil
+		}
+
+		if preserved(filepath.ToSlash(rel)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.IsDir() {
+			dirs = append(dirs, file)
+			return nil
+		}
+
+		if routine.written[filepath.Clean(file)] {
+			return nil
+		}
+
+		if IsVerbose(routine.verbose) {
+			track.Log("removed stale output: %v", rel)
+		}
+
+		return os.Remove(file)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to remove stale output\n%v", err)
+	}
+
+	// deepest directories are removed first, so that parents may become empty
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i]) > len(dirs[j])
+	})
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err == nil && len(entries) == 0 {
+			os.Remove(dir)
+		}
+	}
+
+	return nil
+}
+
+// sourceDirs holds the directories of a project that are read by a build.
+var sourceDirs = []string{"routes", "templates", "static", "data"}
+
+// output will return the absolute path to the output directory. An error is
+// returned if removing files from it could remove the project itself or any of
+// its source directories, or if it is inside of a source directory.
+func (routine *Build) output() (string, error) {
+	root, err := filepath.Abs(routine.path)
+	if err != nil {
+		return "", errors.New("unable to determine absolute path")
+	}
+
+	output := filepath.Join(root, Output())
+
+	if within(output, root) {
+		return "", fmt.Errorf("output directory must not contain the project: %v", Output())
+	}
+
+	for _, dir := range sourceDirs {
+		source := filepath.Join(root, dir)
+
+		if within(output, source) || within(source, output) {
+			return "", fmt.Errorf("output directory must not contain or be inside of the `%v` directory: %v", dir, Output())
+		}
+	}
+
+	return output, nil
+}
+
+// within will return true if path is equal to or inside of dir.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// built will return the absolute path to the output directory of the current
+// build. It is safe to call while a build is running.
+func (routine *Build) built() string {
+	routine.mu.RLock()
+	defer routine.mu.RUnlock()
+
+	return routine.outputDir
+}
+
+// preserved will return true if the slash separated path, relative to the output
+// directory, or any directory containing it matches a pattern in config.State.Preserve.
+func preserved(rel string) bool {
+	for _, pattern := range config.State.Preserve {
+		pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+
+		for current := rel; current != "." && current != "/"; current = path.Dir(current) {
+			matched, err := path.Match(pattern, current)
+			if err == nil && matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,177 @@
+package routine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmkng/onyx/config"
+)
+
+func TestPreserved(t *testing.T) {
+	config.State.Preserve = []string{"CNAME", ".well-known/*", "/assets/"}
+	defer func() { config.State.Preserve = nil }()
+
+	cases := map[string]bool{
+		"CNAME":                       true,
+		".well-known/security.txt":    true,
+		"assets/img/logo.png":         true,
+		"posts/CNAME":                 false,
+		"index.html":                  false,
+		".well-known/deeper/file.txt": true,
+	}
+
+	for rel, expected := range cases {
+		if preserved(rel) != expected {
+			t.Logf("expected %v for %v", expected, rel)
+			t.Fail()
+		}
+	}
+}
+
+func TestBuildPrune(t *testing.T) {
+	stale := func(t *testing.T, dir string, rel ...string) string {
+		full := filepath.Join(append([]string{dir, "build"}, rel...)...)
+
+		err := os.MkdirAll(filepath.Dir(full), 0755)
+		if err == nil {
+			err = os.WriteFile(full, []byte("stale"), 0644)
+		}
+
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		return full
+	}
+
+	t.Run("orphaned output is removed and preserved files are kept", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName:                   "preserve: [CNAME, .well-known/*]\n",
+			filepath.Join("routes", "index.html"): "home",
+		})
+
+		orphan := stale(t, dir, "old", "index.html")
+		cname := stale(t, dir, "CNAME")
+		wellKnown := stale(t, dir, ".well-known", "security.txt")
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if exists(orphan) || exists(filepath.Dir(orphan)) {
+			t.Log("expected orphaned output and its directory to be removed")
+			t.Fail()
+		}
+
+		if !exists(cname) || !exists(wellKnown) {
+			t.Log("expected preserved files to be kept")
+			t.Fail()
+		}
+
+		if !exists(filepath.Join(dir, "build", "index.html")) {
+			t.Log("expected rendered output to be kept")
+			t.Fail()
+		}
+	})
+
+	t.Run("clean removes preserved files", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName:                   "preserve: [CNAME]\n",
+			filepath.Join("routes", "index.html"): "home",
+		})
+
+		cname := stale(t, dir, "CNAME")
+
+		routine := Build{path: dir, clean: true}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if exists(cname) {
+			t.Log("expected output directory to be wiped")
+			t.Fail()
+		}
+	})
+
+	t.Run("output is kept when the project path is relative", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("routes", "index.html"): "home",
+		})
+
+		wd, err := os.Getwd()
+		if err == nil {
+			err = os.Chdir(filepath.Dir(dir))
+		}
+
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		defer os.Chdir(wd)
+
+		routine := Build{path: filepath.Base(dir)}
+
+		err = routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if !exists(filepath.Join(dir, "build", "index.html")) {
+			t.Log("expected rendered output to be kept")
+			t.Fail()
+		}
+	})
+
+	t.Run("output directory may not contain the project", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName:                   "output: .\n",
+			filepath.Join("routes", "index.html"): "home",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err == nil {
+			t.Log("expected error for output directory containing the project")
+			t.Fail()
+		}
+
+		if !exists(filepath.Join(dir, "routes", "index.html")) {
+			t.Log("expected project to be untouched")
+			t.Fail()
+		}
+	})
+	t.Run("output directory may not be inside of a source directory", func(t *testing.T) {
+		for _, output := range []string{"static", "static/site", "routes/build"} {
+			dir := project(t, map[string]string{
+				config.YamlLongName:                   "output: " + output + "\n",
+				filepath.Join("routes", "index.html"): "home",
+				filepath.Join("static", "site.css"):   "body {}",
+			})
+
+			routine := Build{path: dir}
+
+			err := routine.build()
+			if err == nil {
+				t.Logf("expected error for output directory %v", output)
+				t.Fail()
+			}
+
+			if !exists(filepath.Join(dir, "static", "site.css")) || !exists(filepath.Join(dir, "routes", "index.html")) {
+				t.Logf("expected sources to be untouched for output directory %v", output)
+				t.Fail()
+			}
+		}
+	})
+}
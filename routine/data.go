@@ -0,0 +1,118 @@
+package routine
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmkng/onyx/convert/csv"
+	"github.com/jmkng/onyx/convert/json"
+	"github.com/jmkng/onyx/convert/yaml"
+)
+
+// loadData will parse every recognized file in the data directory of the project
+// at root, and return a tree that follows the directory layout. For example, the
+// file `data/team/members.yaml` is found at tree["team"]["members"]. An error is
+// returned if a file is malformed or two files resolve to the same key.
+func loadData(root string) (map[string]any, error) {
+	tree := make(map[string]any)
+
+	toData := filepath.Join(root, "data")
+
+	_, err := os.Stat(toData)
+	if err != nil && errors.Is(err, os.ErrNotExist) {
+		return tree, nil
+	}
+
+	err = filepath.WalkDir(toData, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == toData {
+			return nil
+		}
+
+		if isIgnored(path) != nil {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+
+		ext := filepath.Ext(path)
+
+		switch ext {
+		case ".yaml", ".yml", ".json", ".csv":
+		default:
+			return nil
+		}
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read data file: %v", path)
+		}
+
+		var value any
+
+		switch ext {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(raw, &value)
+		case ".json":
+			err = json.Unmarshal(raw, &value)
+		case ".csv":
+			var rows []map[string]string
+			err = csv.Unmarshal(raw, &rows)
+			value = rows
+		}
+
+		if err != nil {
+			return fmt.Errorf("malformed data file: %v\n%v", path, err)
+		}
+
+		rel, err := filepath.Rel(toData, path)
+		if err != nil {
+			return fmt.Errorf("unable to determine relative path to data file: %v", path)
+		}
+
+		segments := strings.Split(rel, string(filepath.Separator))
+		key := strings.TrimSuffix(segments[len(segments)-1], ext)
+
+		branch := tree
+		for _, v := range segments[:len(segments)-1] {
+			next, ok := branch[v]
+			if !ok {
+				next = make(map[string]any)
+				branch[v] = next
+			}
+
+			asMap, ok := next.(map[string]any)
+			if !ok {
+				return fmt.Errorf("data file conflicts with directory of the same name: %v", path)
+			}
+
+			branch = asMap
+		}
+
+		if _, exists := branch[key]; exists {
+			return fmt.Errorf("data file conflicts with another file or directory of the same name: %v", path)
+		}
+
+		branch[key] = value
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
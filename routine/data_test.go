@@ -0,0 +1,107 @@
+package routine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmkng/onyx/config"
+)
+
+func TestLoadData(t *testing.T) {
+	t.Run("data files are nested by directory", func(t *testing.T) {
+		dir, err := config.CreateTemp(t, "")
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		files := map[string]string{
+			filepath.Join("data", "team", "members.yaml"): "- name: one\n- name: two\n",
+			filepath.Join("data", "nav.json"):             `{"home": "/"}`,
+			filepath.Join("data", "pricing.csv"):          "plan,price\nbasic,10\n",
+		}
+
+		for path, content := range files {
+			full := filepath.Join(dir, path)
+
+			err := os.MkdirAll(filepath.Dir(full), 0755)
+			if err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+
+			err = os.WriteFile(full, []byte(content), 0644)
+			if err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+		}
+
+		tree, err := loadData(dir)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		team, ok := tree["team"].(map[string]any)
+		if !ok {
+			t.Logf("expected `team` to be a map, received %T", tree["team"])
+			t.FailNow()
+		}
+
+		members, ok := team["members"].([]any)
+		if !ok || len(members) != 2 {
+			t.Logf("expected `team.members` to hold two members, received %v", team["members"])
+			t.Fail()
+		}
+
+		nav, ok := tree["nav"].(map[string]any)
+		if !ok || nav["home"] != "/" {
+			t.Logf("expected `nav.home` to be `/`, received %v", tree["nav"])
+			t.Fail()
+		}
+
+		pricing, ok := tree["pricing"].([]map[string]string)
+		if !ok || len(pricing) != 1 || pricing[0]["price"] != "10" {
+			t.Logf("expected `pricing` to hold one row, received %v", tree["pricing"])
+			t.Fail()
+		}
+	})
+
+	t.Run("missing data directory returns an empty tree", func(t *testing.T) {
+		dir, err := config.CreateTemp(t, "")
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		tree, err := loadData(dir)
+		if err != nil || len(tree) != 0 {
+			t.Fail()
+		}
+	})
+
+	t.Run("malformed file returns an error naming the file", func(t *testing.T) {
+		name := filepath.Join("data", "broken.json")
+
+		dir, err := config.CreateTemp(t, name)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		err = os.WriteFile(filepath.Join(dir, name), []byte("{"), 0644)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		_, err = loadData(dir)
+		if err == nil || !strings.Contains(err.Error(), "broken.json") {
+			t.Logf("expected error naming `broken.json`, received %v", err)
+			t.Fail()
+		}
+	})
+}
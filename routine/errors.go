@@ -0,0 +1,80 @@
+package routine
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// sourceError describes a problem with a specific file in the project, and
+// optionally the line that caused it.
+type sourceError struct {
+	path string
+	line int
+	err  error
+}
+
+func (e *sourceError) Error() string {
+	if e.line > 0 {
+		return fmt.Sprintf("%v:%v\n%v", e.path, e.line, e.err)
+	}
+
+	return fmt.Sprintf("%v\n%v", e.path, e.err)
+}
+
+func (e *sourceError) Unwrap() error {
+	return e.err
+}
+
+// templateLocation matches the name and line of a template in an error returned
+// from the html/template package, such as `template: base.tmpl:3:5: executing...`.
+var templateLocation = regexp.MustCompile(`template: ([^:]+):(\d+)`)
+
+// templateError will wrap an error returned from the html/template package in a
+// sourceError. The template named in the error is matched against the base name
+// of each file in files, or its path without an extension, and fallback is used
+// if no file matches. When fallback is used, offset is added to the line to
+// account for front matter.
+func templateError(err error, files []string, fallback string, offset int) error {
+	result := &sourceError{
+		path: fallback,
+		err:  err,
+	}
+
+	match := templateLocation.FindStringSubmatch(err.Error())
+	if match == nil {
+		return result
+	}
+
+	line, convErr := strconv.Atoi(match[2])
+	if convErr == nil {
+		result.line = line + offset
+	}
+
+	for _, v := range files {
+		// partials are named by their path without an extension
+		stem := strings.TrimSuffix(filepath.ToSlash(v), filepath.Ext(v))
+
+		if filepath.Base(v) == match[1] || strings.HasSuffix(stem, "/"+match[1]) {
+			result.path = v
+			result.line = line
+			break
+		}
+	}
+
+	return result
+}
+
+// locate will return the file and line described by an error along with the
+// underlying error, or empty values and err if it does not describe a location.
+func locate(err error) (string, int, error) {
+	var source *sourceError
+	if errors.As(err, &source) {
+		return source.path, source.line, source.err
+	}
+
+	return "", 0, err
+}
@@ -0,0 +1,40 @@
+package routine
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestTemplateError(t *testing.T) {
+	t.Run("template file and line are found", func(t *testing.T) {
+		err := errors.New(`template: post.tmpl:12:3: executing "post.tmpl" at <.Missing>: nil pointer`)
+
+		wrapped := templateError(err, []string{"templates/base.tmpl", "templates/post.tmpl"}, "routes/a.md", 4)
+
+		file, line, cause := locate(wrapped)
+		if file != "templates/post.tmpl" || line != 12 || cause != err {
+			t.Logf("unexpected location: %v:%v", file, line)
+			t.Fail()
+		}
+	})
+
+	t.Run("fallback is used for an unknown template, and the line is offset", func(t *testing.T) {
+		err := errors.New(`template: prerender:4: unexpected "}" in operand`)
+
+		file, line, _ := locate(templateError(err, nil, "routes/a.tmpl", 3))
+		if file != "routes/a.tmpl" || line != 7 {
+			t.Logf("unexpected location: %v:%v", file, line)
+			t.Fail()
+		}
+	})
+
+	t.Run("location is found through wrapped errors", func(t *testing.T) {
+		err := fmt.Errorf("outer\n%w", templateError(errors.New("inner"), nil, "routes/a.tmpl", 0))
+
+		file, _, _ := locate(err)
+		if file != "routes/a.tmpl" {
+			t.Fail()
+		}
+	})
+}
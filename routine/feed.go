@@ -0,0 +1,522 @@
+package routine
+
+import (
+	_json "encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmkng/onyx/config"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// Recognized feed formats, along with the name of the file each is written to.
+const (
+	FeedRss  = "rss"
+	FeedAtom = "atom"
+	FeedJson = "json"
+
+	rssFile  = "index.xml"
+	atomFile = "atom.xml"
+	jsonFile = "feed.json"
+)
+
+// Number of words in the summary of a feed item when no summary is given.
+const SummaryWords = 70
+
+// feedItem is a single entry in a feed, independent of its format.
+type feedItem struct {
+	title string
+	link  string
+	date  time.Time
+	body  string
+}
+
+// feedSource describes a listing that a feed is written for, such as a group.
+type feedSource struct {
+	title   string
+	link    string
+	dir     string
+	members []map[string]any
+	options config.Feed
+}
+
+// feeds will write a feed in each format listed in config.State.Feeds for every
+// group, and for every taxonomy term if requested. Nothing is written if no
+// formats are listed.
+func (routine *Build) feeds(shared map[string]any) error {
+	formats := config.State.Feeds.Formats
+	if len(formats) == 0 {
+		return nil
+	}
+
+	for _, format := range formats {
+		if format != FeedRss && format != FeedAtom && format != FeedJson {
+			return fmt.Errorf("unrecognized feed format in configuration file: %v", format)
+		}
+	}
+
+	if config.State.BaseURL == "" {
+		return errors.New("feeds require `baseURL` to be set in configuration file")
+	}
+
+	sources, err := routine.feedSources(shared)
+	if err != nil {
+		return err
+	}
+
+	for _, source := range sources {
+		if !*source.options.Enabled {
+			continue
+		}
+
+		items := feedItems(source.members, source.options)
+
+		for _, format := range formats {
+			var data []byte
+			var file string
+
+			switch format {
+			case FeedRss:
+				file = rssFile
+				data, err = rss(source, items)
+			case FeedAtom:
+				file = atomFile
+				data, err = atom(source, items)
+			case FeedJson:
+				file = jsonFile
+				data, err = jsonFeed(source, items)
+			}
+
+			if err != nil {
+				return fmt.Errorf("unable to create %v feed for %v\n%v", format, source.title, err)
+			}
+
+			err = routine.write(filepath.Join(source.dir, file), data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// feedSources will return every group in shared that a feed is written for,
+// followed by every taxonomy term if config.State.Feeds.Taxonomies is set.
+func (routine *Build) feedSources(shared map[string]any) ([]feedSource, error) {
+	caser := cases.Title(language.English)
+
+	toRoutes := filepath.Join(routine.path, "routes")
+	output := filepath.Join(routine.path, Output())
+
+	var names []string
+	for name := range shared {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var result []feedSource
+
+	for _, name := range names {
+		members, ok := shared[name].([]map[string]any)
+		if !ok || len(members) == 0 {
+			continue
+		}
+
+		path, _ := members[0]["***Path"].(string)
+
+		rel, err := diff(toRoutes, filepath.Dir(path))
+		if err != nil {
+			return nil, fmt.Errorf("unable to determine feed location for group: %v", name)
+		}
+
+		// the index of a group lists the group, and is not an item of its feed
+		var items []map[string]any
+		for _, v := range members {
+			path, _ := v["***Path"].(string)
+			if strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) != "index" {
+				items = append(items, v)
+			}
+		}
+
+		if len(items) == 0 {
+			continue
+		}
+
+		options := config.State.Feeds.Feed("")
+		for group := range config.State.Feeds.Groups {
+			if caser.String(group) == name {
+				options = config.State.Feeds.Feed(group)
+			}
+		}
+
+		result = append(result, feedSource{
+			title:   feedTitle(name),
+			link:    "/" + filepath.ToSlash(rel) + "/",
+			dir:     filepath.Join(output, rel),
+			members: items,
+			options: options,
+		})
+	}
+
+	if !config.State.Feeds.Taxonomies {
+		return result, nil
+	}
+
+	taxonomies, _ := shared["Taxonomies"].(map[string][]*Term)
+
+	for _, taxonomy := range config.State.Taxonomies {
+		for _, term := range taxonomies[taxonomy.Name] {
+			link := strings.TrimSuffix(term.Link, "index.html")
+
+			result = append(result, feedSource{
+				title:   feedTitle(term.Name),
+				link:    link,
+				dir:     filepath.Join(output, slugify(taxonomy.Name), term.Slug),
+				members: term.Pages,
+				options: config.State.Feeds.Feed(""),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// feedTitle will return the title of a feed for a listing, prefixed with the
+// site title if one is configured.
+func feedTitle(name string) string {
+	if config.State.Title == "" {
+		return name
+	}
+
+	return fmt.Sprintf("%v - %v", config.State.Title, name)
+}
+
+// feedItems will convert the members of a listing to feed items. Members are
+// expected to be sorted newest first.
+func feedItems(members []map[string]any, options config.Feed) []feedItem {
+	if *options.Limit > 0 && len(members) > *options.Limit {
+		members = members[:*options.Limit]
+	}
+
+	var result []feedItem
+
+	for _, member := range members {
+		title, _ := member["Title"].(string)
+		link, _ := member["Link"].(string)
+		date, _ := member["Date"].(string)
+
+		item := feedItem{
+			title: title,
+			link:  absURL(link),
+			body:  content(member),
+		}
+
+		item.date, _ = parseDate(date)
+
+		if !*options.Full {
+			item.body = summary(member)
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
+
+// summary will return the `summary` or `description` of a group member, or the
+// beginning of its content as plain text if neither is given.
+func summary(member map[string]any) string {
+	for _, key := range []string{"Summary", "Description"} {
+		if value, ok := member[key].(string); ok && value != "" {
+			return value
+		}
+	}
+
+	return truncateWords(plainify(content(member)), SummaryWords)
+}
+
+// content will return the content of a group member as a string.
+func content(member map[string]any) string {
+	switch value := member["Content"].(type) {
+	case template.HTML:
+		return string(value)
+	case string:
+		return value
+	default:
+		return ""
+	}
+}
+
+// absURL will join a site relative link with config.State.BaseURL. Links that
+// are already absolute are returned unchanged.
+func absURL(link string) string {
+	if isAbsURL(link) {
+		return link
+	}
+
+	base := strings.TrimSuffix(config.State.BaseURL, "/")
+
+	if link == "" {
+		return base + "/"
+	}
+
+	if !strings.HasPrefix(link, "/") {
+		link = "/" + link
+	}
+
+	return base + link
+}
+
+// markup matches an HTML tag.
+var markup = regexp.MustCompile(`<[^>]*>`)
+
+// hidden matches a link that is hidden from assistive technology, such as the
+// permalink anchor of a heading, along with its text.
+var hidden = regexp.MustCompile(`(?s)<a\s[^>]*\baria-hidden="true"[^>]*>.*?</a>`)
+
+// plainify will remove HTML tags from a string and unescape any entities. Hidden
+// links, such as the anchors of headings, are removed along with their text.
+func plainify(s string) string {
+	s = hidden.ReplaceAllString(s, "")
+
+	return html.UnescapeString(markup.ReplaceAllString(s, ""))
+}
+
+// truncateWords will shorten a string to the given number of words, followed by
+// an ellipsis if any were removed. Whitespace between words is collapsed.
+func truncateWords(s string, words int) string {
+	fields := strings.Fields(s)
+	if len(fields) <= words {
+		return strings.Join(fields, " ")
+	}
+
+	return strings.Join(fields[:words], " ") + "…"
+}
+
+// latest will return the date of the newest item, or the current time if no
+// item has a date.
+func latest(items []feedItem) time.Time {
+	var result time.Time
+
+	for _, item := range items {
+		if item.date.After(result) {
+			result = item.date
+		}
+	}
+
+	if result.IsZero() {
+		return time.Now()
+	}
+
+	return result
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Guid        rssGuid `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+	Description string  `xml:"description"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          rssLink   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// rss will create an RSS 2.0 document from the given items.
+func rss(source feedSource, items []feedItem) ([]byte, error) {
+	document := rssDocument{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         source.title,
+			Link:          absURL(source.link),
+			Description:   source.title,
+			LastBuildDate: latest(items).Format(time.RFC1123Z),
+			Self: rssLink{
+				Href: absURL(source.link + rssFile),
+				Rel:  "self",
+				Type: "application/rss+xml",
+			},
+		},
+	}
+
+	for _, item := range items {
+		entry := rssItem{
+			Title:       item.title,
+			Link:        item.link,
+			Guid:        rssGuid{IsPermaLink: true, Value: item.link},
+			Description: item.body,
+		}
+
+		if !item.date.IsZero() {
+			entry.PubDate = item.date.Format(time.RFC1123Z)
+		}
+
+		document.Channel.Items = append(document.Channel.Items, entry)
+	}
+
+	return marshalXml(document)
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title   string    `xml:"title"`
+	Id      string    `xml:"id"`
+	Link    atomLink  `xml:"link"`
+	Updated string    `xml:"updated"`
+	Summary *atomText `xml:"summary,omitempty"`
+	Content *atomText `xml:"content,omitempty"`
+}
+
+type atomDocument struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	Id      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// atom will create an Atom 1.0 document from the given items.
+func atom(source feedSource, items []feedItem) ([]byte, error) {
+	updated := latest(items)
+
+	document := atomDocument{
+		Title:   source.title,
+		Id:      absURL(source.link),
+		Updated: updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: absURL(source.link + atomFile), Rel: "self"},
+			{Href: absURL(source.link), Rel: "alternate"},
+		},
+	}
+
+	for _, item := range items {
+		date := item.date
+		if date.IsZero() {
+			date = updated
+		}
+
+		entry := atomEntry{
+			Title:   item.title,
+			Id:      item.link,
+			Link:    atomLink{Href: item.link},
+			Updated: date.Format(time.RFC3339),
+		}
+
+		text := &atomText{Type: "html", Value: item.body}
+		if *source.options.Full {
+			entry.Content = text
+		} else {
+			entry.Summary = text
+		}
+
+		document.Entries = append(document.Entries, entry)
+	}
+
+	return marshalXml(document)
+}
+
+// marshalXml will encode a document as indented XML with a declaration.
+func marshalXml(document any) ([]byte, error) {
+	data, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+type jsonItem struct {
+	Id            string `json:"id"`
+	Url           string `json:"url"`
+	Title         string `json:"title,omitempty"`
+	ContentHtml   string `json:"content_html,omitempty"`
+	Summary       string `json:"summary,omitempty"`
+	DatePublished string `json:"date_published,omitempty"`
+}
+
+type jsonDocument struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageUrl string     `json:"home_page_url"`
+	FeedUrl     string     `json:"feed_url"`
+	Items       []jsonItem `json:"items"`
+}
+
+// jsonFeed will create a JSON Feed 1.1 document from the given items.
+func jsonFeed(source feedSource, items []feedItem) ([]byte, error) {
+	document := jsonDocument{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       source.title,
+		HomePageUrl: absURL(source.link),
+		FeedUrl:     absURL(source.link + jsonFile),
+		Items:       []jsonItem{},
+	}
+
+	for _, item := range items {
+		entry := jsonItem{
+			Id:    item.link,
+			Url:   item.link,
+			Title: item.title,
+		}
+
+		if *source.options.Full {
+			entry.ContentHtml = item.body
+		} else {
+			entry.Summary = item.body
+		}
+
+		if !item.date.IsZero() {
+			entry.DatePublished = item.date.Format(time.RFC3339)
+		}
+
+		document.Items = append(document.Items, entry)
+	}
+
+	return _json.MarshalIndent(document, "", "  ")
+}
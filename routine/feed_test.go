@@ -0,0 +1,193 @@
+package routine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmkng/onyx/config"
+)
+
+func TestTruncateWords(t *testing.T) {
+	t.Run("words beyond the limit are removed", func(t *testing.T) {
+		result := truncateWords("one  two\nthree four", 2)
+		if result != "one two…" {
+			t.Logf("received %v", result)
+			t.Fail()
+		}
+	})
+
+	t.Run("short strings are unchanged", func(t *testing.T) {
+		result := truncateWords("one two", 2)
+		if result != "one two" {
+			t.Logf("received %v", result)
+			t.Fail()
+		}
+	})
+}
+
+func TestPlainify(t *testing.T) {
+	result := plainify("<p>one &amp; <em>two</em></p>")
+	if result != "one & two" {
+		t.Logf("received %v", result)
+		t.Fail()
+	}
+}
+
+func TestBuildFeeds(t *testing.T) {
+	files := func(conf string) map[string]string {
+		return map[string]string{
+			config.YamlLongName:                        conf,
+			filepath.Join("routes", "posts", "one.md"): "---\ntitle: one\ndate: 2023-01-01\n---\n<b>first</b> post",
+			filepath.Join("routes", "posts", "two.md"): "---\ntitle: two\ndate: 2023-02-01\nsummary: second\n---\nsecond post",
+		}
+	}
+
+	t.Run("feeds are written for each group", func(t *testing.T) {
+		dir := project(t, files("baseURL: https://example.com/\nfeeds:\n  formats: [rss, atom, json]\n"))
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		for _, name := range []string{"index.xml", "atom.xml", "feed.json"} {
+			data, err := os.ReadFile(filepath.Join(dir, "build", "posts", name))
+			if err != nil {
+				t.Logf("expected feed %v to exist", name)
+				t.Fail()
+				continue
+			}
+
+			feed := string(data)
+			if !strings.Contains(feed, "https://example.com/posts/one/index.html") {
+				t.Logf("expected absolute item link in %v", name)
+				t.Fail()
+			}
+
+			if strings.Index(feed, "two") > strings.Index(feed, "one") {
+				t.Logf("expected newest item first in %v", name)
+				t.Fail()
+			}
+		}
+
+		rss, _ := os.ReadFile(filepath.Join(dir, "build", "posts", "index.xml"))
+		if !strings.Contains(string(rss), "<description>first post</description>") {
+			t.Logf("expected plain summary, received %v", string(rss))
+			t.Fail()
+		}
+	})
+
+	t.Run("groups may be configured", func(t *testing.T) {
+		dir := project(t, files("baseURL: https://example.com\nfeeds:\n  formats: [rss]\n  groups:\n    posts:\n      limit: 1\n      full: true\n"))
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		rss, _ := os.ReadFile(filepath.Join(dir, "build", "posts", "index.xml"))
+		if strings.Count(string(rss), "<item>") != 1 || !strings.Contains(string(rss), "second post") {
+			t.Logf("expected one item with full content, received %v", string(rss))
+			t.Fail()
+		}
+	})
+
+	t.Run("partial group options keep the shared options", func(t *testing.T) {
+		dir := project(t, files("baseURL: https://example.com\nfeeds:\n  formats: [rss]\n  limit: 1\n  groups:\n    posts:\n      enabled: true\n"))
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		rss, _ := os.ReadFile(filepath.Join(dir, "build", "posts", "index.xml"))
+		if strings.Count(string(rss), "<item>") != 1 {
+			t.Logf("expected shared limit of one item, received %v", string(rss))
+			t.Fail()
+		}
+	})
+
+	t.Run("the index of a group is not an item", func(t *testing.T) {
+		routes := files("baseURL: https://example.com\nfeeds:\n  formats: [rss]\n")
+		routes[filepath.Join("routes", "posts", "index.md")] = "---\ntitle: listing\n---\nevery post"
+
+		dir := project(t, routes)
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		rss, _ := os.ReadFile(filepath.Join(dir, "build", "posts", "index.xml"))
+		if strings.Count(string(rss), "<item>") != 2 || strings.Contains(string(rss), "listing") {
+			t.Logf("expected index to be excluded, received %v", string(rss))
+			t.Fail()
+		}
+	})
+
+	t.Run("heading anchors are left out of summaries", func(t *testing.T) {
+		routes := files("baseURL: https://example.com\nfeeds:\n  formats: [rss, json]\nmarkdown:\n  anchors:\n    enabled: true\n")
+		routes[filepath.Join("routes", "posts", "one.md")] = "---\ntitle: one\ndate: 2023-01-01\n---\n## Hello\n\nworld"
+
+		dir := project(t, routes)
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		for _, name := range []string{"index.xml", "feed.json"} {
+			feed, _ := os.ReadFile(filepath.Join(dir, "build", "posts", name))
+			if !strings.Contains(string(feed), "Hello world") || strings.Contains(string(feed), "Hello #") {
+				t.Logf("expected summary without anchor in %v, received %v", name, string(feed))
+				t.Fail()
+			}
+		}
+	})
+
+	t.Run("groups may be disabled", func(t *testing.T) {
+		dir := project(t, files("baseURL: https://example.com\nfeeds:\n  formats: [rss]\n  groups:\n    posts:\n      enabled: false\n"))
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if exists(filepath.Join(dir, "build", "posts", "index.xml")) {
+			t.Log("expected feed to be disabled")
+			t.Fail()
+		}
+	})
+
+	t.Run("base url is required", func(t *testing.T) {
+		dir := project(t, files("feeds:\n  formats: [rss]\n"))
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err == nil {
+			t.Log("expected error for missing base url")
+			t.Fail()
+		}
+	})
+}
@@ -0,0 +1,285 @@
+package routine
+
+import (
+	_json "encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmkng/onyx/config"
+	"github.com/jmkng/onyx/convert/json"
+	"github.com/jmkng/onyx/convert/toml"
+	"github.com/jmkng/onyx/convert/yaml"
+)
+
+// Recognized front matter delimiters.
+const (
+	yamlDelimiter = "---"
+	tomlDelimiter = "+++"
+)
+
+// Recognized front matter formats.
+const (
+	formatYaml = "yaml"
+	formatToml = "toml"
+	formatJson = "json"
+)
+
+// byteOrderMark may precede the content of a UTF-8 encoded file.
+const byteOrderMark = "\uFEFF"
+
+// matter holds the front matter and content found in a file.
+type matter struct {
+	// format is the format of the front matter, or an empty string if the
+	// file has no front matter.
+	format string
+	// head is the front matter without delimiters.
+	head string
+	// headLine is the line in the file where head begins.
+	headLine int
+	// body is the content that follows the front matter.
+	body string
+	// bodyLine is the line in the file where body begins.
+	bodyLine int
+}
+
+// split will separate the front matter in a file from its content. Front matter
+// is only recognized when its opening delimiter is on the first line of the file,
+// which may begin with a byte order mark. Line endings are normalized to `\n`.
+// An error is returned if the front matter is never closed.
+func split(path, data string) (matter, error) {
+	data = strings.TrimPrefix(data, byteOrderMark)
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+
+	first := data
+	if index := strings.Index(data, "\n"); index != -1 {
+		first = data[:index]
+	}
+
+	first = strings.TrimRight(first, " \t")
+
+	var delimiter, kind string
+
+	switch {
+	case first == yamlDelimiter:
+		delimiter, kind = yamlDelimiter, formatYaml
+	case first == tomlDelimiter:
+		delimiter, kind = tomlDelimiter, formatToml
+	case isJsonObject(data):
+		return splitJson(path, data)
+	default:
+		return matter{body: data, bodyLine: 1}, nil
+	}
+
+	lines := strings.SplitAfter(data, "\n")
+
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != delimiter {
+			continue
+		}
+
+		return matter{
+			format:   kind,
+			head:     strings.Join(lines[1:i], ""),
+			headLine: 2,
+			body:     strings.Join(lines[i+1:], ""),
+			bodyLine: i + 2,
+		}, nil
+	}
+
+	return matter{}, &sourceError{
+		path: path,
+		line: 1,
+		err:  fmt.Errorf("%v front matter is missing a closing delimiter `%v`", kind, delimiter),
+	}
+}
+
+// splitJson will separate a JSON object at the beginning of data from the content
+// that follows it.
+func splitJson(path, data string) (matter, error) {
+	end := jsonEnd(data)
+	if end == -1 {
+		return matter{}, &sourceError{
+			path: path,
+			line: 1,
+			err:  errors.New("json front matter is missing a closing brace"),
+		}
+	}
+
+	head := data[:end]
+	body := data[end:]
+
+	// the rest of the line holding the closing brace is discarded if it is blank
+	if index := strings.Index(body, "\n"); index != -1 && strings.TrimSpace(body[:index]) == "" {
+		body = body[index+1:]
+	}
+
+	return matter{
+		format:   formatJson,
+		head:     head,
+		headLine: 1,
+		body:     body,
+		bodyLine: strings.Count(data[:len(data)-len(body)], "\n") + 1,
+	}, nil
+}
+
+// isJsonObject will return true if the given string begins with a JSON object.
+// Only the opening brace and first key are examined, which is enough to tell
+// JSON apart from a template action such as `{{ .Title }}`.
+func isJsonObject(data string) bool {
+	if !strings.HasPrefix(data, "{") {
+		return false
+	}
+
+	rest := strings.TrimLeft(data[1:], " \t\r\n")
+
+	return strings.HasPrefix(rest, "\"") || strings.HasPrefix(rest, "}")
+}
+
+// jsonEnd will return the index immediately after the JSON object that the given
+// string begins with, or -1 if the object is never closed.
+func jsonEnd(data string) int {
+	depth := 0
+	inString := false
+	escaped := false
+
+	for i, v := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case v == '\\':
+				escaped = true
+			case v == '"':
+				inString = false
+			}
+
+			continue
+		}
+
+		switch v {
+		case '"':
+			inString = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return -1
+}
+
+// matterLocation matches the line number in an error returned from the yaml or toml
+// packages, such as `yaml: line 3: mapping values are not allowed in this context`.
+var matterLocation = regexp.MustCompile(`line (\d+)`)
+
+// unmarshal will parse the front matter into a map of typed values. If the front
+// matter is malformed, a sourceError is returned that holds the file at path and
+// the line that caused the problem.
+func (m matter) unmarshal(path string) (map[string]any, error) {
+	result := make(map[string]any)
+
+	var err error
+
+	switch m.format {
+	case formatYaml:
+		err = yaml.Unmarshal([]byte(m.head), &result)
+	case formatToml:
+		err = toml.Unmarshal([]byte(m.head), &result)
+	case formatJson:
+		err = json.Unmarshal([]byte(m.head), &result)
+	default:
+		return nil, fmt.Errorf("unrecognized front matter format: %v", m.format)
+	}
+
+	if err != nil {
+		line := matterLine(err, m.head)
+		if line > 0 {
+			line += m.headLine - 1
+		}
+
+		return nil, &sourceError{
+			path: path,
+			line: line,
+			err:  fmt.Errorf("malformed %v front matter\n%v", m.format, err),
+		}
+	}
+
+	// YAML will leave the map nil when the front matter is empty.
+	if result == nil {
+		result = make(map[string]any)
+	}
+
+	return result, nil
+}
+
+// matterLine will return the line described by an error returned while parsing
+// front matter, or 0 if the error does not describe a line.
+func matterLine(err error, head string) int {
+	var syntaxErr *_json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return offsetLine(head, syntaxErr.Offset)
+	}
+
+	var typeErr *_json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return offsetLine(head, typeErr.Offset)
+	}
+
+	match := matterLocation.FindStringSubmatch(err.Error())
+	if match != nil {
+		line, convErr := strconv.Atoi(match[1])
+		if convErr == nil {
+			return line
+		}
+	}
+
+	return 0
+}
+
+// offsetLine will return the line that contains the byte at offset in data.
+func offsetLine(data string, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	return strings.Count(data[:offset], "\n") + 1
+}
+
+// dateString will return a string representation of a date found in front matter.
+// Dates parsed as timestamps by YAML or TOML are formatted with config.DateFmt, or
+// time.RFC3339 if they contain a time.
+func dateString(value any) (string, error) {
+	switch date := value.(type) {
+	case string:
+		return date, nil
+	case time.Time:
+		hour, min, sec := date.Clock()
+		if hour == 0 && min == 0 && sec == 0 && date.Nanosecond() == 0 {
+			return date.Format(config.DateFmt), nil
+		}
+
+		return date.Format(time.RFC3339), nil
+	default:
+		return "", fmt.Errorf("expected a date, received: %v", value)
+	}
+}
+
+// parseDate will parse a date from front matter, which is expected to follow
+// config.DateFmt or time.RFC3339.
+func parseDate(value string) (time.Time, error) {
+	date, err := time.Parse(config.DateFmt, value)
+	if err == nil {
+		return date, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
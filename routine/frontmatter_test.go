@@ -0,0 +1,267 @@
+package routine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplit(t *testing.T) {
+	t.Run("valid delimiters '---' with tabs '\t' on the closing line are recognized", func(t *testing.T) {
+		mock := `---
+		title: test
+		---`
+
+		result, err := split("test.md", mock)
+		if err != nil || result.format != formatYaml {
+			t.Fail()
+		}
+	})
+
+	t.Run("valid delimiters '---' are recognized", func(t *testing.T) {
+		mock := "---\ntitle: test\n---"
+
+		result, err := split("test.md", mock)
+		if err != nil || result.format != formatYaml {
+			t.Fail()
+		}
+	})
+
+	t.Run("one too few delimiters '--' is not front matter", func(t *testing.T) {
+		mock := "--\ntitle: test\n---"
+
+		result, err := split("test.md", mock)
+		if err != nil || result.format != "" || result.body != mock {
+			t.Fail()
+		}
+	})
+
+	t.Run("one too many delimiters '----' is not front matter", func(t *testing.T) {
+		mock := `----
+		title: test
+		---`
+
+		result, err := split("test.md", mock)
+		if err != nil || result.format != "" {
+			t.Fail()
+		}
+	})
+
+	t.Run("valid delimiters '+++' are recognized", func(t *testing.T) {
+		mock := "+++\ntitle = \"test\"\n+++"
+
+		result, err := split("test.md", mock)
+		if err != nil || result.format != formatToml {
+			t.Fail()
+		}
+	})
+
+	t.Run("string beginning with a json object is recognized", func(t *testing.T) {
+		mock := "{\"title\": \"test {}\"}\nbody"
+
+		result, err := split("test.md", mock)
+		if err != nil || result.format != formatJson {
+			t.Fail()
+		}
+	})
+
+	t.Run("string beginning with a template action is not front matter", func(t *testing.T) {
+		mock := "{{ range .Posts }}{{ .Title }}{{ end }}"
+
+		result, err := split("test.md", mock)
+		if err != nil || result.format != "" {
+			t.Fail()
+		}
+	})
+
+	t.Run("mismatched delimiters '+++' and '---' return an error", func(t *testing.T) {
+		mock := "+++\ntitle = \"test\"\n---"
+
+		_, err := split("test.md", mock)
+
+		file, line, _ := locate(err)
+		if err == nil || file != "test.md" || line != 1 {
+			t.Fail()
+		}
+	})
+
+	t.Run("horizontal rules in content are not front matter", func(t *testing.T) {
+		mock := "# Title\n\n---\n\nsection\n\n---\n"
+
+		result, err := split("test.md", mock)
+		if err != nil || result.format != "" || result.body != mock {
+			t.Fail()
+		}
+	})
+
+	t.Run("horizontal rules after front matter remain in content", func(t *testing.T) {
+		mock := "---\ntitle: test\n---\none\n\n---\n\ntwo"
+
+		result, err := split("test.md", mock)
+		if err != nil || result.body != "one\n\n---\n\ntwo" {
+			t.Logf("unexpected body: %q", result.body)
+			t.Fail()
+		}
+	})
+
+	t.Run("short strings do not panic", func(t *testing.T) {
+		for _, v := range []string{"", "-", "--", "{"} {
+			_, err := split("test.md", v)
+			if err != nil && v != "{" {
+				t.Logf("unexpected error for %q: %v", v, err)
+				t.Fail()
+			}
+		}
+	})
+
+	t.Run("YAML is extracted", func(t *testing.T) {
+		mock := "---\ntitle: test\nauthor: test\n---\nbody"
+
+		result, err := split("test.md", mock)
+		if err != nil {
+			t.Logf("split returned err: %v", err)
+			t.FailNow()
+		}
+
+		if result.head != "title: test\nauthor: test\n" || result.body != "body" || result.bodyLine != 5 {
+			t.Logf("body/head are unexpected values\nhead: %v\nbody: %v", result.head, result.body)
+			t.Fail()
+		}
+	})
+
+	t.Run("TOML is extracted", func(t *testing.T) {
+		mock := "+++\ntitle = \"test\"\n+++\nbody"
+
+		result, err := split("test.md", mock)
+		if err != nil {
+			t.Logf("split returned err: %v", err)
+			t.FailNow()
+		}
+
+		if result.head != "title = \"test\"\n" || result.body != "body" || result.bodyLine != 4 {
+			t.Logf("body/head are unexpected values\nhead: %v\nbody: %v", result.head, result.body)
+			t.Fail()
+		}
+	})
+
+	t.Run("JSON is extracted", func(t *testing.T) {
+		mock := `{"title": "a \"}\" b"}` + "\nbody"
+
+		result, err := split("test.md", mock)
+		if err != nil {
+			t.Logf("split returned err: %v", err)
+			t.FailNow()
+		}
+
+		if result.head != `{"title": "a \"}\" b"}` || result.body != "body" || result.bodyLine != 2 {
+			t.Logf("body/head are unexpected values\nhead: %v\nbody: %v", result.head, result.body)
+			t.Fail()
+		}
+	})
+
+	t.Run("CRLF line endings and a byte order mark are handled", func(t *testing.T) {
+		mock := "\uFEFF---\r\ntitle: test\r\n---\r\nbody\r\n"
+
+		result, err := split("test.md", mock)
+		if err != nil {
+			t.Logf("split returned err: %v", err)
+			t.FailNow()
+		}
+
+		if result.format != formatYaml || result.head != "title: test\n" || result.body != "body\n" {
+			t.Logf("body/head are unexpected values\nhead: %q\nbody: %q", result.head, result.body)
+			t.Fail()
+		}
+	})
+}
+
+func TestUnmarshal(t *testing.T) {
+	t.Run("values are typed", func(t *testing.T) {
+		head := "tags: [go, web]\ndraft: true\nweight: 3\nauthor:\n  name: test\n"
+
+		data, err := matter{format: formatYaml, head: head, headLine: 2}.unmarshal("test.md")
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		tags, ok := data["tags"].([]any)
+		if !ok || len(tags) != 2 || tags[0] != "go" {
+			t.Logf("unexpected tags: %#v", data["tags"])
+			t.Fail()
+		}
+
+		if data["draft"] != true || data["weight"] != 3 {
+			t.Logf("unexpected draft or weight: %#v %#v", data["draft"], data["weight"])
+			t.Fail()
+		}
+
+		author, ok := data["author"].(map[string]any)
+		if !ok || author["name"] != "test" {
+			t.Logf("unexpected author: %#v", data["author"])
+			t.Fail()
+		}
+	})
+
+	t.Run("toml and json are typed", func(t *testing.T) {
+		toml, err := matter{format: formatToml, head: "weight = 3\ntags = [\"go\"]\n", headLine: 2}.unmarshal("test.md")
+		if err != nil || toml["weight"] != int64(3) {
+			t.Logf("unexpected toml: %#v %v", toml, err)
+			t.Fail()
+		}
+
+		json, err := matter{format: formatJson, head: `{"draft": true, "weight": 3}`, headLine: 1}.unmarshal("test.md")
+		if err != nil || json["draft"] != true || json["weight"] != float64(3) {
+			t.Logf("unexpected json: %#v %v", json, err)
+			t.Fail()
+		}
+	})
+
+	t.Run("malformed front matter returns the file and line", func(t *testing.T) {
+		cases := map[string]string{
+			formatYaml: "---\ntitle: test\nbad: a: b\n---\n",
+			formatToml: "+++\ntitle = \"test\"\nbad = tru\n+++\n",
+			formatJson: "{\n\"title\": \"test\",\n\"bad\": }",
+		}
+
+		for kind, data := range cases {
+			result, err := split("test.md", data)
+			if err != nil || result.format != kind {
+				t.Logf("%v: unexpected split result: %v", kind, err)
+				t.FailNow()
+			}
+
+			_, err = result.unmarshal("test.md")
+
+			file, line, _ := locate(err)
+			if file != "test.md" || line != 3 {
+				t.Logf("%v: expected test.md:3, received %v:%v", kind, file, line)
+				t.Fail()
+			}
+		}
+	})
+}
+
+func TestDateString(t *testing.T) {
+	t.Run("timestamps are formatted", func(t *testing.T) {
+		date := time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+
+		result, err := dateString(date)
+		if err != nil || result != "2022-01-02" {
+			t.Logf("expected 2022-01-02, received %v", result)
+			t.Fail()
+		}
+
+		result, err = dateString(date.Add(time.Hour))
+		if err != nil || result != "2022-01-02T01:00:00Z" {
+			t.Logf("expected 2022-01-02T01:00:00Z, received %v", result)
+			t.Fail()
+		}
+	})
+
+	t.Run("other types are rejected", func(t *testing.T) {
+		_, err := dateString(3)
+		if err == nil {
+			t.Fail()
+		}
+	})
+}
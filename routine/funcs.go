@@ -0,0 +1,553 @@
+package routine
+
+import (
+	"bytes"
+	_json "encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmkng/onyx/config"
+	"github.com/jmkng/onyx/convert/md"
+)
+
+// funcs will return the functions available to every template, including the
+// prerender pass of `.tmpl` routes. Arguments are ordered so that the value being
+// operated on comes last, which allows functions to be chained with a pipeline,
+// such as `{{ .Content | plainify | truncate 120 }}`.
+func funcs() template.FuncMap {
+	return template.FuncMap{
+		"dateFormat":  dateFormat,
+		"now":         time.Now,
+		"truncate":    truncate,
+		"plainify":    func(value any) string { return plainify(toString(value)) },
+		"markdownify": markdownify,
+		"slugify":     func(value any) string { return slugify(toString(value)) },
+		"urlize":      urlize,
+		"default":     defaultValue,
+		"dict":        dict,
+		"slice":       func(items ...any) []any { return items },
+		"join":        join,
+		"where":       where,
+		"sortBy":      sortBy,
+		"first":       first,
+		"last":        last,
+		"safeHTML":    func(value any) template.HTML { return template.HTML(toString(value)) },
+		"safeURL":     func(value any) template.URL { return template.URL(toString(value)) },
+		"jsonify":     jsonify,
+		"relURL":      func(value any) string { return relURL(toString(value)) },
+		"absURL":      func(value any) string { return absURL(toString(value)) },
+		"add":         func(a, b any) (any, error) { return arithmetic("add", a, b) },
+		"sub":         func(a, b any) (any, error) { return arithmetic("sub", a, b) },
+		"mul":         func(a, b any) (any, error) { return arithmetic("mul", a, b) },
+		"div":         func(a, b any) (any, error) { return arithmetic("div", a, b) },
+		"mod":         func(a, b any) (any, error) { return arithmetic("mod", a, b) },
+		// replaced with a working function by partialSet.with
+		"partial": func(name string, context ...any) (template.HTML, error) {
+			return "", fmt.Errorf("partial `%v` is unavailable in this template", name)
+		},
+	}
+}
+
+// dateFormat will format a date with a Go layout, such as `{{ dateFormat "Jan 2, 2006" .Date }}`.
+// The date may be a time.Time or a string following config.DateFmt or time.RFC3339.
+func dateFormat(layout string, value any) (string, error) {
+	switch date := value.(type) {
+	case time.Time:
+		return date.Format(layout), nil
+	case string:
+		if date == "" {
+			return "", nil
+		}
+
+		parsed, err := parseDate(date)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse date `%v`, expected format %v", date, config.DateFmt)
+		}
+
+		return parsed.Format(layout), nil
+	default:
+		return "", fmt.Errorf("expected a date, received: %v", value)
+	}
+}
+
+// truncate will shorten a string to the given number of characters, followed by
+// an ellipsis if any were removed, such as `{{ truncate 120 .Description }}`.
+func truncate(length int, value any) string {
+	s := toString(value)
+
+	if utf8.RuneCountInString(s) <= length {
+		return s
+	}
+
+	return strings.TrimSpace(string([]rune(s)[:length])) + "…"
+}
+
+// markdownify will convert markdown to HTML. A single paragraph is unwrapped
+// so that the result can be used inline, such as `<h1>{{ markdownify .Title }}</h1>`.
+func markdownify(value any) (template.HTML, error) {
+	var buf bytes.Buffer
+
+	err := md.Unmarshal([]byte(toString(value)), &buf)
+	if err != nil {
+		return "", err
+	}
+
+	result := strings.TrimSpace(buf.String())
+
+	if strings.HasPrefix(result, "<p>") && strings.HasSuffix(result, "</p>") && strings.Count(result, "<p>") == 1 {
+		result = strings.TrimSuffix(strings.TrimPrefix(result, "<p>"), "</p>")
+	}
+
+	return template.HTML(result), nil
+}
+
+// urlize will make a string safe to use in a link by lowercasing it, replacing
+// whitespace with `-` and escaping any other unsafe characters.
+func urlize(value any) string {
+	fields := strings.Fields(strings.ToLower(toString(value)))
+
+	return url.PathEscape(strings.Join(fields, "-"))
+}
+
+// defaultValue will return value, or fallback if value is empty, such as
+// `{{ default "Untitled" .Title }}`.
+func defaultValue(fallback, value any) any {
+	if isEmpty(value) {
+		return fallback
+	}
+
+	return value
+}
+
+// dict will create a map from alternating keys and values, such as
+// `{{ template "partials/card" dict "Title" .Title "Link" .Link }}`.
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict requires an even number of arguments")
+	}
+
+	result := make(map[string]any, len(pairs)/2)
+
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings, received: %v", pairs[i])
+		}
+
+		result[key] = pairs[i+1]
+	}
+
+	return result, nil
+}
+
+// join will concatenate the items of a list with a separator, such as
+// `{{ join ", " .Tags }}`.
+func join(separator string, collection any) (string, error) {
+	list, err := toList(collection)
+	if err != nil {
+		return "", err
+	}
+
+	var items []string
+	for i := 0; i < list.Len(); i++ {
+		items = append(items, toString(list.Index(i).Interface()))
+	}
+
+	return strings.Join(items, separator), nil
+}
+
+// where will return the items of a list where the value of key matches, such as
+// `{{ range where .Posts "Author" "jane" }}`. An operator may be given before the
+// value, which is one of `==`, `!=`, `<`, `<=`, `>`, `>=` or `in`.
+func where(collection any, key string, args ...any) (any, error) {
+	operator := "=="
+
+	var match any
+
+	switch len(args) {
+	case 1:
+		match = args[0]
+	case 2:
+		op, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("where operator must be a string, received: %v", args[0])
+		}
+
+		operator, match = op, args[1]
+	default:
+		return nil, errors.New("where requires a list, a key, an optional operator and a value")
+	}
+
+	list, err := toList(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	result := reflect.MakeSlice(list.Type(), 0, list.Len())
+
+	for i := 0; i < list.Len(); i++ {
+		item := list.Index(i)
+
+		value, _ := field(item.Interface(), key)
+
+		ok, err := compare(operator, value, match)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			result = reflect.Append(result, item)
+		}
+	}
+
+	return result.Interface(), nil
+}
+
+// sortBy will return a copy of a list sorted by the value of key, in ascending
+// order unless `desc` is given, such as `{{ range sortBy .Posts "Title" }}`.
+func sortBy(collection any, key string, order ...string) (any, error) {
+	list, err := toList(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	descending := false
+	if len(order) > 0 {
+		switch order[0] {
+		case "asc":
+		case "desc":
+			descending = true
+		default:
+			return nil, fmt.Errorf("sortBy order must be `asc` or `desc`, received: %v", order[0])
+		}
+	}
+
+	result := reflect.MakeSlice(list.Type(), list.Len(), list.Len())
+	reflect.Copy(result, list)
+
+	sort.SliceStable(result.Interface(), func(i, j int) bool {
+		a, _ := field(result.Index(i).Interface(), key)
+		b, _ := field(result.Index(j).Interface(), key)
+
+		if descending {
+			return less(b, a)
+		}
+
+		return less(a, b)
+	})
+
+	return result.Interface(), nil
+}
+
+// first will return the first count items of a list, such as `{{ range first 5 .Posts }}`.
+func first(count int, collection any) (any, error) {
+	list, err := toList(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	if count > list.Len() {
+		count = list.Len()
+	}
+
+	if count < 0 {
+		count = 0
+	}
+
+	return list.Slice(0, count).Interface(), nil
+}
+
+// last will return the last count items of a list, such as `{{ range last 5 .Posts }}`.
+func last(count int, collection any) (any, error) {
+	list, err := toList(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	if count > list.Len() {
+		count = list.Len()
+	}
+
+	if count < 0 {
+		count = 0
+	}
+
+	return list.Slice(list.Len()-count, list.Len()).Interface(), nil
+}
+
+// jsonify will encode a value as JSON, which is safe to use inside of a script
+// element, such as `<script type="application/ld+json">{{ jsonify .Data.schema }}</script>`.
+func jsonify(value any) (template.JS, error) {
+	data, err := _json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return template.JS(data), nil
+}
+
+// relURL will return a site relative link, prefixed with the path of
+// config.State.BaseURL so that the link works when a site is hosted below the
+// root of a domain.
+func relURL(link string) string {
+	if isAbsURL(link) {
+		return link
+	}
+
+	prefix := "/"
+
+	base, err := url.Parse(config.State.BaseURL)
+	if err == nil && base.Path != "" {
+		prefix = base.Path
+	}
+
+	result := path.Join(prefix, link)
+	if strings.HasSuffix(link, "/") && !strings.HasSuffix(result, "/") {
+		result += "/"
+	}
+
+	return result
+}
+
+// isAbsURL will return true if the link includes a scheme or host.
+func isAbsURL(link string) bool {
+	parsed, err := url.Parse(link)
+
+	return err == nil && (parsed.Scheme != "" || parsed.Host != "")
+}
+
+// arithmetic will apply an operator to two numbers. The result is an int when
+// both numbers are whole, otherwise a float64.
+func arithmetic(operator string, a, b any) (any, error) {
+	x, okA := toFloat(a)
+	y, okB := toFloat(b)
+
+	if !okA || !okB {
+		return nil, fmt.Errorf("%v requires two numbers, received: %v, %v", operator, a, b)
+	}
+
+	_, intA := toInt(a)
+	_, intB := toInt(b)
+
+	var result float64
+
+	switch operator {
+	case "add":
+		result = x + y
+	case "sub":
+		result = x - y
+	case "mul":
+		result = x * y
+	case "div", "mod":
+		if y == 0 {
+			return nil, fmt.Errorf("%v by zero", operator)
+		}
+
+		if operator == "mod" {
+			if !intA || !intB {
+				return nil, errors.New("mod requires two whole numbers")
+			}
+
+			return int(x) % int(y), nil
+		}
+
+		result = x / y
+	}
+
+	if intA && intB && result == float64(int(result)) {
+		return int(result), nil
+	}
+
+	return result, nil
+}
+
+// toString will convert a value found in a template to a string.
+func toString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case template.HTML:
+		return string(v)
+	case template.URL:
+		return string(v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// toFloat will convert a number to a float64.
+func toFloat(value any) (float64, bool) {
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// toList will return the reflected value of a slice or array.
+func toList(collection any) (reflect.Value, error) {
+	list := reflect.ValueOf(collection)
+
+	switch list.Kind() {
+	case reflect.Slice:
+		return list, nil
+	case reflect.Array:
+		result := reflect.MakeSlice(reflect.SliceOf(list.Type().Elem()), list.Len(), list.Len())
+		reflect.Copy(result, list)
+
+		return result, nil
+	case reflect.Invalid:
+		return reflect.ValueOf([]any{}), nil
+	default:
+		return reflect.Value{}, fmt.Errorf("expected a list, received: %v", collection)
+	}
+}
+
+// field will return the value of key in a map, or the field or method of a
+// struct with the same name.
+func field(item any, key string) (any, bool) {
+	value := reflect.ValueOf(item)
+
+	if method := value.MethodByName(key); method.IsValid() && method.Type().NumIn() == 0 && method.Type().NumOut() >= 1 {
+		return method.Call(nil)[0].Interface(), true
+	}
+
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, false
+		}
+
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+
+		result := value.MapIndex(reflect.ValueOf(key).Convert(value.Type().Key()))
+		if !result.IsValid() {
+			return nil, false
+		}
+
+		return result.Interface(), true
+	case reflect.Struct:
+		result := value.FieldByName(key)
+		if !result.IsValid() || !result.CanInterface() {
+			return nil, false
+		}
+
+		return result.Interface(), true
+	default:
+		return nil, false
+	}
+}
+
+// compare will apply a comparison operator to two values found in a template.
+func compare(operator string, a, b any) (bool, error) {
+	switch operator {
+	case "==", "=", "eq":
+		return equal(a, b), nil
+	case "!=", "ne":
+		return !equal(a, b), nil
+	case "<", "lt":
+		return less(a, b), nil
+	case "<=", "le":
+		return less(a, b) || equal(a, b), nil
+	case ">", "gt":
+		return less(b, a), nil
+	case ">=", "ge":
+		return less(b, a) || equal(a, b), nil
+	case "in":
+		list, err := toList(b)
+		if err != nil {
+			return false, err
+		}
+
+		for i := 0; i < list.Len(); i++ {
+			if equal(a, list.Index(i).Interface()) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	default:
+		return false, fmt.Errorf("unrecognized operator: %v", operator)
+	}
+}
+
+// equal will return true if two values are equal, comparing numbers by value
+// regardless of their type.
+func equal(a, b any) bool {
+	x, okA := toFloat(a)
+	y, okB := toFloat(b)
+
+	if okA && okB {
+		return x == y
+	}
+
+	if reflect.TypeOf(a) != nil && reflect.TypeOf(a) == reflect.TypeOf(b) && reflect.TypeOf(a).Comparable() {
+		return a == b
+	}
+
+	return toString(a) == toString(b)
+}
+
+// less will return true if a sorts before b. Numbers and dates are compared by
+// value, and everything else as a string. Missing values sort last.
+func less(a, b any) bool {
+	if a == nil || b == nil {
+		return a != nil
+	}
+
+	x, okA := toFloat(a)
+	y, okB := toFloat(b)
+
+	if okA && okB {
+		return x < y
+	}
+
+	if x, ok := a.(time.Time); ok {
+		if y, ok := b.(time.Time); ok {
+			return x.Before(y)
+		}
+	}
+
+	return toString(a) < toString(b)
+}
+
+// isEmpty will return true if a value is nil, false, zero or has no length.
+func isEmpty(value any) bool {
+	if value == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
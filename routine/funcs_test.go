@@ -0,0 +1,98 @@
+package routine
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmkng/onyx/config"
+)
+
+// execute will render a template using the built-in functions.
+func execute(t *testing.T, text string, data any) string {
+	t.Helper()
+
+	tmpl, err := template.New("test").Funcs(funcs()).Parse(text)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var buf bytes.Buffer
+
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	return buf.String()
+}
+
+func TestFuncs(t *testing.T) {
+	config.State.BaseURL = "https://example.com/docs/"
+	defer func() { config.State.BaseURL = "" }()
+
+	posts := []map[string]any{
+		{"Title": "b", "Author": "jane", "Weight": 2},
+		{"Title": "a", "Author": "john", "Weight": 3},
+		{"Title": "c", "Author": "jane", "Weight": 1},
+	}
+
+	cases := map[string]string{
+		`{{ dateFormat "Jan 2, 2006" "2023-04-05" }}`:                             "Apr 5, 2023",
+		`{{ "one two three" | truncate 7 }}`:                                      "one two…",
+		`{{ plainify "<p>a &amp; b</p>" }}`:                                       "a &amp; b",
+		`{{ markdownify "*a*" }}`:                                                 "<em>a</em>",
+		`{{ slugify "Hello, World" }}`:                                            "hello-world",
+		`{{ urlize "Hello World?" }}`:                                             "hello-world%3F",
+		`{{ default "none" "" }}|{{ default "none" "some" }}`:                     "none|some",
+		`{{ with dict "A" 1 "B" 2 }}{{ .B }}{{ end }}`:                            "2",
+		`{{ join "," (slice "a" "b") }}`:                                          "a,b",
+		`{{ range where .Posts "Author" "jane" }}{{ .Title }}{{ end }}`:           "bc",
+		`{{ range where .Posts "Weight" ">" 1 }}{{ .Title }}{{ end }}`:            "ba",
+		`{{ range sortBy .Posts "Weight" }}{{ .Title }}{{ end }}`:                 "cba",
+		`{{ range sortBy .Posts "Title" "desc" }}{{ .Title }}{{ end }}`:           "cba",
+		`{{ range first 2 .Posts }}{{ .Title }}{{ end }}`:                         "ba",
+		`{{ range last 1 .Posts }}{{ .Title }}{{ end }}`:                          "c",
+		`{{ safeHTML "<b>a</b>" }}`:                                               "<b>a</b>",
+		`<a href="{{ safeURL "tel:123" }}">`:                                      `<a href="tel:123">`,
+		`<script>var a = {{ jsonify (dict "a" 1) }};</script>`:                    `<script>var a = {"a":1};</script>`,
+		`{{ relURL "/a/" }}|{{ absURL "/a/" }}`:                                   "/docs/a/|https://example.com/docs/a/",
+		`{{ add 1 2 }}|{{ sub 1 2 }}|{{ mul 2 1.5 }}|{{ div 3 2 }}|{{ mod 5 3 }}`: "3|-1|3|1.5|2",
+	}
+
+	for text, expected := range cases {
+		result := execute(t, text, map[string]any{"Posts": posts})
+		if result != expected {
+			t.Logf("expected %v for %v, received %v", expected, text, result)
+			t.Fail()
+		}
+	}
+}
+
+func TestBuildFuncs(t *testing.T) {
+	t.Run("functions are available to routes and templates", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("templates", "base.tmpl"):  "{{ .Title | slugify }}:{{ .Content }}",
+			filepath.Join("routes", "index.tmpl"):    "---\ntitle: Hello World\n---\n{{ range sortBy .Posts \"Title\" }}{{ .Title | truncate 5 }}{{ end }}",
+			filepath.Join("routes", "posts", "a.md"): "---\ntitle: a\n---\na",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		index, _ := os.ReadFile(filepath.Join(dir, "build", "index.html"))
+		if string(index) != "hello-world:a" {
+			t.Logf("received %v", string(index))
+			t.Fail()
+		}
+	})
+}
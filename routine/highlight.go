@@ -0,0 +1,90 @@
+package routine
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jmkng/onyx/config"
+	"github.com/jmkng/onyx/convert/md"
+	"github.com/jmkng/onyx/track"
+)
+
+func NewHighlight() *Highlight {
+	routine := &Highlight{
+		fs: flag.NewFlagSet("highlight", flag.ContinueOnError),
+	}
+
+	routine.fs.StringVar(&routine.path, "path", WdOrPanic(), "Path to the project whose highlight style is used.")
+	routine.fs.StringVar(&routine.style, "style", "", "Name of the chroma style, which overrides the configured style.")
+	routine.fs.StringVar(&routine.output, "output", "", "Path to the stylesheet relative to the project, such as static/syntax.css. The stylesheet is printed if no path is given.")
+	routine.fs.BoolVar(&routine.verbose, "verbose", false, "Display more detailed information")
+
+	return routine
+}
+
+// Highlight writes the stylesheet matching the classes of highlighted code blocks.
+type Highlight struct {
+	fs      *flag.FlagSet
+	path    string
+	style   string
+	output  string
+	verbose bool
+}
+
+func (routine *Highlight) Name() string {
+	return routine.fs.Name()
+}
+
+func (routine *Highlight) Parse(args []string) error {
+	return routine.fs.Parse(args)
+}
+
+func (routine *Highlight) Execute() error {
+	style := routine.style
+
+	// a project is only required to read the configured style
+	if style == "" {
+		err := Setup(routine.path)
+		if err != nil {
+			return err
+		}
+
+		style = config.State.Markdown.Highlight.StyleName()
+	}
+
+	var buf bytes.Buffer
+
+	err := md.CSS(style, &buf)
+	if err != nil {
+		return err
+	}
+
+	if routine.output == "" {
+		fmt.Print(buf.String())
+		return nil
+	}
+
+	dest := routine.output
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(routine.path, dest)
+	}
+
+	err = os.MkdirAll(filepath.Dir(dest), DefDirPerm)
+	if err != nil {
+		return fmt.Errorf("unable to create directory: %v", filepath.Dir(dest))
+	}
+
+	err = os.WriteFile(dest, buf.Bytes(), DefFilePerm)
+	if err != nil {
+		return fmt.Errorf("unable to write file: %v", dest)
+	}
+
+	if IsVerbose(routine.verbose) {
+		track.Log(fmt.Sprintf("wrote: %v", dest))
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package routine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmkng/onyx/config"
+)
+
+func TestHighlight(t *testing.T) {
+	t.Run("stylesheet uses the configured style", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName: "markdown:\n  highlight:\n    style: monokai\n",
+		})
+
+		routine := NewHighlight()
+
+		err := routine.Parse([]string{"--path", dir, "--output", filepath.Join("static", "syntax.css")})
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		err = routine.Execute()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		result, err := os.ReadFile(filepath.Join(dir, "static", "syntax.css"))
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		// monokai has a dark background
+		if !strings.Contains(string(result), ".chroma") || !strings.Contains(string(result), "#272822") {
+			t.Logf("received %v", string(result))
+			t.Fail()
+		}
+	})
+
+	t.Run("unknown styles are rejected", func(t *testing.T) {
+		dir := project(t, map[string]string{})
+
+		routine := NewHighlight()
+
+		err := routine.Parse([]string{"--path", dir, "--style", "missing", "--output", "syntax.css"})
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		err = routine.Execute()
+		if err == nil || exists(filepath.Join(dir, "syntax.css")) {
+			t.Log("expected error for unknown style")
+			t.Fail()
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package routine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Template that every layout chain ends with.
+	BaseTemplate = "base.tmpl"
+	// Directory below templates holding the layouts used when a group has none.
+	DefaultLayouts = "_default"
+	// Layout used for the index of a directory.
+	ListLayout = "list.tmpl"
+	// Layout used for every other resource.
+	SingleLayout = "single.tmpl"
+)
+
+// extendsDirective matches the comment at the beginning of a layout that names its
+// parent layout, such as `{{/* extends "article.tmpl" */}}`.
+var extendsDirective = regexp.MustCompile(`^\s*\{\{-?\s*/\*\s*extends\s+"([^"]+)"\s*\*/\s*-?\}\}`)
+
+// layouts will resolve the chain of layouts that begins with the named template,
+// and return the path to each one, starting with the base template. A layout names
+// its parent with a comment on its first line, such as `{{/* extends "article.tmpl" */}}`,
+// and layouts without one extend the base template. Templates parsed later override
+// the `block` regions of their parents. An error naming the chain is returned if a
+// layout is missing or the chain contains a cycle.
+func (routine *Build) layouts(name string) ([]string, error) {
+	toTemplates := filepath.Join(routine.path, "templates")
+
+	chain := []string{}
+	seen := make(map[string]bool)
+
+	for current := name; current != "" && current != BaseTemplate; {
+		current = filepath.ToSlash(filepath.Clean(current))
+
+		if seen[current] {
+			chain = append(chain, current)
+			return nil, fmt.Errorf("layout chain contains a cycle: %v", strings.Join(chain, " -> "))
+		}
+
+		seen[current] = true
+		chain = append(chain, current)
+
+		data, err := os.ReadFile(filepath.Join(toTemplates, filepath.FromSlash(current)))
+		if err != nil {
+			if len(chain) == 1 {
+				return nil, fmt.Errorf("unable to locate template `%v`", current)
+			}
+
+			return nil, fmt.Errorf("unable to locate template `%v` in layout chain: %v", current, strings.Join(chain, " -> "))
+		}
+
+		current = parent(string(data))
+	}
+
+	result := []string{filepath.Join(toTemplates, BaseTemplate)}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		result = append(result, filepath.Join(toTemplates, filepath.FromSlash(chain[i])))
+	}
+
+	return result, nil
+}
+
+// convention will return the layout of a resource that does not request one with
+// front matter. The index of a directory uses `list.tmpl` and every other resource
+// uses `single.tmpl`, found in the directory named after the group of the resource,
+// such as `templates/blog/single.tmpl`, or in `templates/_default/`. An empty string
+// is returned if neither exists, and the resource is rendered with the base template.
+func convention(root string, res resource) string {
+	kind := SingleLayout
+
+	name := strings.TrimSuffix(filepath.Base(res.path), filepath.Ext(res.path))
+	if name == "index" {
+		kind = ListLayout
+	}
+
+	var candidates []string
+	if res.group != "" {
+		candidates = append(candidates, res.group+"/"+kind)
+	}
+
+	candidates = append(candidates, DefaultLayouts+"/"+kind)
+
+	for _, v := range candidates {
+		_, err := os.Stat(filepath.Join(root, "templates", filepath.FromSlash(v)))
+		if err == nil {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// parent will return the parent layout named by the extends directive at the
+// beginning of a layout, or the base template if there is no directive.
+func parent(layout string) string {
+	match := extendsDirective.FindStringSubmatch(strings.TrimPrefix(layout, byteOrderMark))
+	if match == nil {
+		return BaseTemplate
+	}
+
+	return match[1]
+}
+
+// describeChain will return a description of a layout chain, starting with the
+// most specific layout, such as `post.tmpl -> article.tmpl -> base.tmpl`.
+func describeChain(templates []string) string {
+	var names []string
+
+	for i := len(templates) - 1; i >= 0; i-- {
+		names = append(names, filepath.Base(templates[i]))
+	}
+
+	return strings.Join(names, " -> ")
+}
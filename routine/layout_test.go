@@ -0,0 +1,129 @@
+package routine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParent(t *testing.T) {
+	cases := map[string]string{
+		"{{/* extends \"article.tmpl\" */}}\n{{ define \"main\" }}{{ end }}": "article.tmpl",
+		"\n  {{- /* extends \"docs/page.tmpl\" */ -}}":                       "docs/page.tmpl",
+		"{{ define \"main\" }}{{ end }}":                                     BaseTemplate,
+		"text\n{{/* extends \"article.tmpl\" */}}":                           BaseTemplate,
+	}
+
+	for layout, expected := range cases {
+		result := parent(layout)
+		if result != expected {
+			t.Logf("expected %v, received %v", expected, result)
+			t.Fail()
+		}
+	}
+}
+
+func TestBuildLayouts(t *testing.T) {
+	t.Run("layouts override the blocks of their parents", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("templates", "base.tmpl"):    `<main>{{ block "main" . }}base{{ end }}</main>`,
+			filepath.Join("templates", "article.tmpl"): "{{/* extends \"base.tmpl\" */}}\n{{ define \"main\" }}<article>{{ block \"body\" . }}article{{ end }}</article>{{ end }}",
+			filepath.Join("templates", "post.tmpl"):    "{{/* extends \"article.tmpl\" */}}\n{{ define \"body\" }}{{ .Content }}{{ end }}",
+			filepath.Join("routes", "post.md"):         "---\ntemplate: post.tmpl\n---\npost",
+			filepath.Join("routes", "about.md"):        "---\ntemplate: article.tmpl\n---\nabout",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		post, _ := os.ReadFile(filepath.Join(dir, "build", "post", "index.html"))
+		if string(post) != "<main><article><p>post</p>\n</article></main>" {
+			t.Logf("received %v", string(post))
+			t.Fail()
+		}
+
+		about, _ := os.ReadFile(filepath.Join(dir, "build", "about", "index.html"))
+		if string(about) != "<main><article>article</article></main>" {
+			t.Logf("received %v", string(about))
+			t.Fail()
+		}
+	})
+
+	t.Run("cycles are reported with the chain", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("templates", "a.tmpl"): "{{/* extends \"b.tmpl\" */}}",
+			filepath.Join("templates", "b.tmpl"): "{{/* extends \"a.tmpl\" */}}",
+			filepath.Join("routes", "index.md"):  "---\ntemplate: a.tmpl\n---\nindex",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err == nil || !strings.Contains(err.Error(), "a.tmpl -> b.tmpl -> a.tmpl") {
+			t.Logf("expected cycle error, received %v", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("missing parents are reported with the chain", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("templates", "a.tmpl"): "{{/* extends \"missing.tmpl\" */}}",
+			filepath.Join("routes", "index.md"):  "---\ntemplate: a.tmpl\n---\nindex",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err == nil || !strings.Contains(err.Error(), "a.tmpl -> missing.tmpl") {
+			t.Logf("expected missing parent error, received %v", err)
+			t.Fail()
+		}
+	})
+}
+
+func TestBuildConvention(t *testing.T) {
+	t.Run("layouts are found by group and kind", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("templates", "base.tmpl"):               `{{ block "main" . }}{{ .Content }}{{ end }}`,
+			filepath.Join("templates", "blog", "single.tmpl"):     `{{ define "main" }}blog single{{ end }}`,
+			filepath.Join("templates", "blog", "list.tmpl"):       `{{ define "main" }}blog list{{ end }}`,
+			filepath.Join("templates", "_default", "single.tmpl"): `{{ define "main" }}default single{{ end }}`,
+			filepath.Join("templates", "other.tmpl"):              `{{ define "main" }}explicit{{ end }}`,
+			filepath.Join("routes", "blog", "index.html"):         "index",
+			filepath.Join("routes", "blog", "a.md"):               "a",
+			filepath.Join("routes", "blog", "b.md"):               "---\ntemplate: other.tmpl\n---\nb",
+			filepath.Join("routes", "docs", "c.md"):               "c",
+			filepath.Join("routes", "index.html"):                 "home",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		expected := map[string]string{
+			filepath.Join("blog", "index.html"):      "blog list",
+			filepath.Join("blog", "a", "index.html"): "blog single",
+			filepath.Join("blog", "b", "index.html"): "explicit",
+			filepath.Join("docs", "c", "index.html"): "default single",
+			"index.html":                             "home",
+		}
+
+		for path, content := range expected {
+			result, _ := os.ReadFile(filepath.Join(dir, "build", path))
+			if string(result) != content {
+				t.Logf("expected %v in %v, received %v", content, path, string(result))
+				t.Fail()
+			}
+		}
+	})
+}
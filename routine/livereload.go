@@ -0,0 +1,244 @@
+package routine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+)
+
+// EventsPath is the path of the Server-Sent Events endpoint used to notify
+// browsers that the site was rebuilt.
+const EventsPath = "/__onyx/events"
+
+// reloadScript is injected into every HTML response from the serve routine. It
+// listens for events from the server and reloads the page, or only the
+// stylesheets when a CSS file is the only thing that changed. When a rebuild
+// fails, the error is shown in an overlay until the next successful build.
+const reloadScript = `<script>
+(function () {
+	var source = new EventSource("` + EventsPath + `");
+	var overlayId = "onyx-error-overlay";
+
+	function clearOverlay() {
+		var overlay = document.getElementById(overlayId);
+		if (overlay) {
+			overlay.remove();
+		}
+	}
+
+	function showOverlay(problem) {
+		clearOverlay();
+
+		var overlay = document.createElement("div");
+		overlay.id = overlayId;
+		overlay.style.cssText = "position:fixed;inset:0;z-index:2147483647;overflow:auto;" +
+			"padding:2rem;background:rgba(20,20,20,0.95);color:#f0f0f0;" +
+			"font:14px/1.5 ui-monospace,SFMono-Regular,Menlo,Consolas,monospace;";
+
+		var heading = document.createElement("div");
+		heading.style.cssText = "color:#ff6b6b;font-size:18px;margin-bottom:1rem;";
+		heading.textContent = "Build failed";
+		overlay.appendChild(heading);
+
+		if (problem.file) {
+			var location = document.createElement("div");
+			location.style.cssText = "color:#ffd166;margin-bottom:1rem;";
+			location.textContent = problem.line ? problem.file + ":" + problem.line : problem.file;
+			overlay.appendChild(location);
+		}
+
+		var detail = document.createElement("pre");
+		detail.style.cssText = "white-space:pre-wrap;margin:0;";
+		detail.textContent = problem.message;
+		overlay.appendChild(detail);
+
+		document.body.appendChild(overlay);
+	}
+
+	source.addEventListener("reload", function () {
+		window.location.reload();
+	});
+
+	source.addEventListener("css", function () {
+		clearOverlay();
+
+		var links = document.querySelectorAll('link[rel="stylesheet"]');
+
+		for (var i = 0; i < links.length; i++) {
+			var url = new URL(links[i].href);
+			url.searchParams.set("onyx", Date.now());
+			links[i].href = url.toString();
+		}
+	});
+
+	source.addEventListener("failure", function (event) {
+		showOverlay(JSON.parse(event.data));
+	});
+})();
+</script>
+`
+
+// problem describes a failed build to the browser.
+type problem struct {
+	Message string `json:"message"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+}
+
+// message is a single Server-Sent Event.
+type message struct {
+	event string
+	data  string
+}
+
+// hub keeps track of every connected browser and broadcasts messages to them.
+type hub struct {
+	clients map[chan message]bool
+	// failure holds the message describing the most recent failed build, and
+	// is sent to new clients so a page loaded after the failure still shows it.
+	failure *message
+	mu      sync.Mutex
+}
+
+// newHub creates an empty hub.
+func newHub() *hub {
+	return &hub{
+		clients: make(map[chan message]bool),
+	}
+}
+
+// subscribe will register a new client and return the channel it receives messages on.
+func (h *hub) subscribe() chan message {
+	client := make(chan message, 1)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.clients[client] = true
+
+	if h.failure != nil {
+		client <- *h.failure
+	}
+
+	return client
+}
+
+// unsubscribe will remove a client from the hub.
+func (h *hub) unsubscribe(client chan message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.clients, client)
+}
+
+// fail will broadcast a failure event describing err, and keep it for clients
+// that connect before the next successful build.
+func (h *hub) fail(err error) {
+	file, line, cause := locate(err)
+
+	data, marshalErr := json.Marshal(problem{
+		Message: cause.Error(),
+		File:    file,
+		Line:    line,
+	})
+	if marshalErr != nil {
+		panic("failed to marshal build failure")
+	}
+
+	msg := message{
+		event: "failure",
+		data:  string(data),
+	}
+
+	h.mu.Lock()
+	h.failure = &msg
+	h.mu.Unlock()
+
+	h.broadcast(msg)
+}
+
+// succeed will discard the most recent failure and broadcast msg.
+func (h *hub) succeed(msg message) {
+	h.mu.Lock()
+	h.failure = nil
+	h.mu.Unlock()
+
+	h.broadcast(msg)
+}
+
+// broadcast will send a message to every connected client. Clients that have not
+// received the previous message yet are skipped, since they will reload anyway.
+func (h *hub) broadcast(msg message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client := range h.clients {
+		select {
+		case client <- msg:
+		default:
+		}
+	}
+}
+
+// ServeHTTP will hold the connection open and stream messages to the client as
+// Server-Sent Events until the client disconnects.
+func (h *hub) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	client := h.subscribe()
+	defer h.unsubscribe(client)
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case msg := <-client:
+			fmt.Fprintf(w, "event: %v\ndata: %v\n\n", msg.event, msg.data)
+			flusher.Flush()
+		}
+	}
+}
+
+// inject will insert the reload script into an HTML document, immediately before
+// the closing body tag if one exists, or at the end of the document.
+func inject(document []byte) []byte {
+	index := bytes.LastIndex(bytes.ToLower(document), []byte("</body>"))
+	if index == -1 {
+		return append(document, reloadScript...)
+	}
+
+	result := make([]byte, 0, len(document)+len(reloadScript))
+	result = append(result, document[:index]...)
+	result = append(result, reloadScript...)
+	result = append(result, document[index:]...)
+
+	return result
+}
+
+// isStylesheetChange will return true if every changed file is a CSS file.
+func isStylesheetChange(changed []string) bool {
+	if len(changed) == 0 {
+		return false
+	}
+
+	for _, v := range changed {
+		if filepath.Ext(v) != ".css" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,41 @@
+package routine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInject(t *testing.T) {
+	t.Run("script is inserted before the closing body tag", func(t *testing.T) {
+		result := string(inject([]byte("<html><body><p>test</p></BODY></html>")))
+
+		if !strings.HasPrefix(result, "<html><body><p>test</p>"+reloadScript) ||
+			!strings.HasSuffix(result, "</BODY></html>") {
+			t.Logf("unexpected result: %v", result)
+			t.Fail()
+		}
+	})
+
+	t.Run("script is appended to a document without a body", func(t *testing.T) {
+		result := string(inject([]byte("<p>test</p>")))
+
+		if result != "<p>test</p>"+reloadScript {
+			t.Logf("unexpected result: %v", result)
+			t.Fail()
+		}
+	})
+}
+
+func TestIsStylesheetChange(t *testing.T) {
+	t.Run("only css files is a stylesheet change", func(t *testing.T) {
+		if !isStylesheetChange([]string{"static/a.css", "static/b.css"}) {
+			t.Fail()
+		}
+	})
+
+	t.Run("any other file is not a stylesheet change", func(t *testing.T) {
+		if isStylesheetChange([]string{"static/a.css", "routes/index.tmpl"}) {
+			t.Fail()
+		}
+	})
+}
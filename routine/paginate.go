@@ -0,0 +1,221 @@
+package routine
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmkng/onyx/config"
+	"github.com/jmkng/onyx/track"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+const (
+	// Front matter key naming the group a resource lists across pages.
+	PaginateKey = "paginate"
+	// Front matter key holding the number of group members on each page.
+	PerPageKey = "perPage"
+	// Number of group members on each page when none is configured.
+	DefPerPage = 10
+)
+
+// Paginator describes one page of a group, and is available to templates
+// as `.Paginator` in a route that requests pagination.
+type Paginator struct {
+	// Items holds the group members on this page.
+	Items []map[string]any
+	// Page is the number of this page, starting at 1.
+	Page int
+	// PerPage is the maximum number of group members on each page.
+	PerPage int
+	// TotalPages is the number of pages.
+	TotalPages int
+	// TotalItems is the number of group members across all pages.
+	TotalItems int
+	// First is a link to the first page.
+	First string
+	// Last is a link to the last page.
+	Last string
+	// Prev is a link to the previous page, or empty on the first page.
+	Prev string
+	// Next is a link to the next page, or empty on the last page.
+	Next string
+}
+
+// HasPrev will return true if there is a page before this one.
+func (p *Paginator) HasPrev() bool {
+	return p.Prev != ""
+}
+
+// HasNext will return true if there is a page after this one.
+func (p *Paginator) HasNext() bool {
+	return p.Next != ""
+}
+
+// paginate will find every resource that requests pagination, and replace it with
+// one resource for each page of the group it lists. The first page keeps the
+// destination of the original resource, and page N is written to `page/N/` below it.
+func (routine *Build) paginate(renderable []resource, shared map[string]any) ([]resource, error) {
+	var result []resource
+
+	for _, res := range renderable {
+		group, perPage, err := routine.pagination(res)
+		if err != nil {
+			return nil, err
+		}
+
+		if group == "" {
+			result = append(result, res)
+			continue
+		}
+
+		caser := cases.Title(language.English)
+
+		members, ok := shared[caser.String(group)].([]map[string]any)
+		if !ok && IsVerbose(routine.verbose) {
+			track.Log(fmt.Sprintf("paginated group `%v` is empty in resource: %v", group, filepath.Base(res.path)))
+		}
+
+		// a listing may live in the directory of the group it lists
+		var listed []map[string]any
+		for _, v := range members {
+			if v["***Path"] != res.path {
+				listed = append(listed, v)
+			}
+		}
+
+		result = append(result, pages(res, listed, perPage)...)
+	}
+
+	return result, nil
+}
+
+// pagination will return the group that a resource lists and the number of
+// members on each page, or an empty group if the resource is not paginated.
+// Front matter takes precedence over config.State.Paginate.
+func (routine *Build) pagination(res resource) (string, int, error) {
+	group := ""
+	perPage := 0
+
+	toRoutes := filepath.Join(routine.path, "routes")
+
+	rel, err := diff(toRoutes, res.path)
+	if err == nil {
+		for _, v := range config.State.Paginate {
+			if filepath.ToSlash(rel) == strings.TrimPrefix(v.Route, "/") {
+				group = v.Group
+				perPage = v.PerPage
+			}
+		}
+	}
+
+	if value, ok := res.data[PaginateKey]; ok {
+		name, ok := value.(string)
+		if !ok {
+			return "", 0, fmt.Errorf("front matter key `%v` must be a group name in resource: %v", PaginateKey, res.path)
+		}
+
+		group = name
+	}
+
+	if value, ok := res.data[PerPageKey]; ok {
+		count, ok := toInt(value)
+		if !ok || count < 1 {
+			return "", 0, fmt.Errorf("front matter key `%v` must be a positive number in resource: %v", PerPageKey, res.path)
+		}
+
+		perPage = count
+	}
+
+	if perPage < 1 {
+		perPage = DefPerPage
+	}
+
+	return group, perPage, nil
+}
+
+// pages will split members into pages of perPage, and return a copy of the
+// listing resource for each page.
+func pages(listing resource, members []map[string]any, perPage int) []resource {
+	total := (len(members) + perPage - 1) / perPage
+	if total == 0 {
+		total = 1
+	}
+
+	base := strings.TrimSuffix(listing.link, "index.html")
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+
+	link := func(page int) string {
+		if page == 1 {
+			return base
+		}
+
+		return path.Join(base, "page", fmt.Sprint(page)) + "/"
+	}
+
+	var result []resource
+
+	for page := 1; page <= total; page++ {
+		start := (page - 1) * perPage
+		end := start + perPage
+		if end > len(members) {
+			end = len(members)
+		}
+
+		paginator := &Paginator{
+			Items:      members[start:end],
+			Page:       page,
+			PerPage:    perPage,
+			TotalPages: total,
+			TotalItems: len(members),
+			First:      link(1),
+			Last:       link(total),
+		}
+
+		if page > 1 {
+			paginator.Prev = link(page - 1)
+		}
+
+		if page < total {
+			paginator.Next = link(page + 1)
+		}
+
+		res := listing
+		res.paginator = paginator
+
+		if page > 1 {
+			dir := filepath.Dir(listing.destination)
+			res.destination = filepath.Join(dir, "page", fmt.Sprint(page), "index.html")
+			res.link = link(page) + "index.html"
+		}
+
+		result = append(result, res)
+	}
+
+	return result
+}
+
+// toInt will convert a number found in front matter to an int. YAML, TOML
+// and JSON each decode numbers to a different type.
+func toInt(value any) (int, bool) {
+	switch number := value.(type) {
+	case int:
+		return number, true
+	case int64:
+		return int(number), true
+	case uint64:
+		return int(number), true
+	case float64:
+		if number != float64(int(number)) {
+			return 0, false
+		}
+
+		return int(number), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,122 @@
+package routine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPages(t *testing.T) {
+	listing := resource{
+		destination: filepath.Join("build", "blog", "index.html"),
+		link:        "/blog/index.html",
+	}
+
+	members := make([]map[string]any, 5)
+
+	t.Run("members are split across pages", func(t *testing.T) {
+		result := pages(listing, members, 2)
+
+		if len(result) != 3 {
+			t.Logf("expected 3 pages, received %v", len(result))
+			t.FailNow()
+		}
+
+		first := result[0].paginator
+		if result[0].destination != listing.destination || first.Prev != "" || first.Next != "/blog/page/2/" {
+			t.Logf("unexpected first page: %+v", first)
+			t.Fail()
+		}
+
+		second := result[1].paginator
+		if result[1].destination != filepath.Join("build", "blog", "page", "2", "index.html") ||
+			second.Prev != "/blog/" || second.Next != "/blog/page/3/" || len(second.Items) != 2 {
+			t.Logf("unexpected second page: %+v", second)
+			t.Fail()
+		}
+
+		last := result[2].paginator
+		if last.HasNext() || !last.HasPrev() || len(last.Items) != 1 || last.TotalPages != 3 || last.TotalItems != 5 {
+			t.Logf("unexpected last page: %+v", last)
+			t.Fail()
+		}
+	})
+
+	t.Run("empty group produces a single page", func(t *testing.T) {
+		result := pages(listing, nil, 2)
+
+		if len(result) != 1 || result[0].paginator.TotalPages != 1 {
+			t.Fail()
+		}
+	})
+}
+
+func TestBuildPaginate(t *testing.T) {
+	t.Run("paginated listing is written for every page", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("routes", "blog.tmpl"):       "---\npaginate: posts\nperPage: 1\n---\n{{ range .Paginator.Items }}{{ .Title }}{{ end }}",
+			filepath.Join("routes", "posts", "one.md"): "---\ntitle: one\ndate: 2022-01-01\n---\none",
+			filepath.Join("routes", "posts", "two.md"): "---\ntitle: two\ndate: 2022-01-02\n---\ntwo",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		expected := map[string]string{
+			filepath.Join(dir, "build", "blog", "index.html"):              "two",
+			filepath.Join(dir, "build", "blog", "page", "2", "index.html"): "one",
+		}
+
+		for path, content := range expected {
+			result, err := os.ReadFile(path)
+			if err != nil || string(result) != content {
+				t.Logf("expected %v in %v, received %v", content, path, string(result))
+				t.Fail()
+			}
+		}
+	})
+
+	t.Run("later pages may not share a link with a route", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("routes", "blog.tmpl"):            "---\npaginate: posts\nperPage: 1\n---\n{{ range .Paginator.Items }}{{ .Title }}{{ end }}",
+			filepath.Join("routes", "blog", "page", "2.md"): "hand written",
+			filepath.Join("routes", "posts", "one.md"):      "---\ntitle: one\ndate: 2022-01-01\n---\none",
+			filepath.Join("routes", "posts", "two.md"):      "---\ntitle: two\ndate: 2022-01-02\n---\ntwo",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err == nil || !strings.Contains(err.Error(), "resources share the same link") {
+			t.Logf("expected error for shared link, received %v", err)
+			t.Fail()
+		}
+	})
+}
+
+func TestToInt(t *testing.T) {
+	t.Run("numbers from each format are converted", func(t *testing.T) {
+		for _, v := range []any{3, int64(3), uint64(3), float64(3)} {
+			result, ok := toInt(v)
+			if !ok || result != 3 {
+				t.Logf("unable to convert %#v", v)
+				t.Fail()
+			}
+		}
+	})
+
+	t.Run("fractions and strings are rejected", func(t *testing.T) {
+		for _, v := range []any{3.5, "3"} {
+			if _, ok := toInt(v); ok {
+				t.Logf("unexpected conversion of %#v", v)
+				t.Fail()
+			}
+		}
+	})
+}
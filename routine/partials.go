@@ -0,0 +1,207 @@
+package routine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// Directory below templates holding partials, which are available to every
+	// template by name, such as `{{ template "partials/header" . }}`.
+	PartialsDir = "partials"
+	// Prefix of templates in the root of the templates directory that are
+	// available to every template by file name, such as `{{ template "base_nav.tmpl" . }}`.
+	SharedPrefix = "base_"
+)
+
+// partialSet holds the templates shared by every resource, which are parsed once
+// for each build.
+type partialSet struct {
+	// templates holds every shared template.
+	templates *template.Template
+	// owners maps the name of each shared template to the file that defines it.
+	owners map[string]string
+}
+
+// partials will parse every file in templates/partials, along with each template
+// in the root of the templates directory with the `base_` prefix. Partials are
+// named by their path below the templates directory without an extension, and
+// `base_` templates by their file name. An error is returned if two files define
+// a template with the same name.
+func (routine *Build) partials() (*partialSet, error) {
+	toTemplates := filepath.Join(routine.path, "templates")
+
+	files, err := sharedFiles(toTemplates)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &partialSet{
+		templates: template.New("").Funcs(funcs()),
+		owners:    make(map[string]string),
+	}
+
+	for _, file := range files {
+		name := filepath.Base(file)
+		if !strings.HasPrefix(name, SharedPrefix) {
+			rel, _ := diff(toTemplates, file)
+			name = strings.TrimSuffix(filepath.ToSlash(rel), filepath.Ext(rel))
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read file: %v", file)
+		}
+
+		parsed, err := template.New(name).Funcs(funcs()).Parse(string(data))
+		if err != nil {
+			return nil, templateError(fmt.Errorf("failed to parse partial\n%v", err), []string{file}, file, 0)
+		}
+
+		for _, v := range parsed.Templates() {
+			if v.Tree == nil || v.Tree.Root == nil {
+				continue
+			}
+
+			if owner, ok := result.owners[v.Name()]; ok {
+				return nil, fmt.Errorf("template `%v` is defined by more than one partial: %v, %v", v.Name(), owner, file)
+			}
+
+			result.owners[v.Name()] = file
+
+			_, err = result.templates.AddParseTree(v.Name(), v.Tree)
+			if err != nil {
+				return nil, fmt.Errorf("unable to add partial `%v`\n%v", v.Name(), err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// sharedFiles will return every file in templates/partials and every `base_`
+// template in the root of the templates directory, sorted by path.
+func sharedFiles(toTemplates string) ([]string, error) {
+	var result []string
+
+	toPartials := filepath.Join(toTemplates, PartialsDir)
+
+	err := filepath.WalkDir(toPartials, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// a project is not required to have partials
+			if path == toPartials && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		if d.Type().IsRegular() && isIgnored(path) == nil {
+			result = append(result, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to walk directory: %v", filepath.Base(toPartials))
+	}
+
+	entries, err := os.ReadDir(toTemplates)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read directory: %v", filepath.Base(toTemplates))
+	}
+
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasPrefix(entry.Name(), SharedPrefix) {
+			result = append(result, filepath.Join(toTemplates, entry.Name()))
+		}
+	}
+
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// with will return a copy of the shared templates that also holds every template
+// in layouts. An error is returned if a template in layouts has the same name as
+// a partial.
+func (p *partialSet) with(layouts *template.Template) (*template.Template, error) {
+	result, err := p.templates.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	if layouts != nil {
+		for _, v := range layouts.Templates() {
+			if v.Tree == nil || v.Tree.Root == nil {
+				continue
+			}
+
+			if owner, ok := p.owners[v.Name()]; ok {
+				return nil, fmt.Errorf("template `%v` is already defined by partial: %v", v.Name(), owner)
+			}
+
+			_, err = result.AddParseTree(v.Name(), v.Tree)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result.Funcs(template.FuncMap{"partial": partial(result)})
+
+	return result, nil
+}
+
+// files will return the path to every file holding a shared template.
+func (p *partialSet) files() []string {
+	var result []string
+
+	seen := make(map[string]bool)
+	for _, v := range p.owners {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// partial will return the `partial` template function for a set of templates,
+// which executes a partial with the given context, such as `{{ partial "header" . }}`.
+// The `partials/` prefix may be omitted from the name.
+func partial(set *template.Template) func(name string, context ...any) (template.HTML, error) {
+	return func(name string, context ...any) (template.HTML, error) {
+		var data any
+		if len(context) > 0 {
+			data = context[0]
+		}
+
+		if set.Lookup(name) == nil && set.Lookup(PartialsDir+"/"+name) != nil {
+			name = PartialsDir + "/" + name
+		}
+
+		if set.Lookup(name) == nil {
+			return "", fmt.Errorf("partial `%v` does not exist", name)
+		}
+
+		var buf bytes.Buffer
+
+		err := set.ExecuteTemplate(&buf, name, data)
+		if err != nil {
+			return "", err
+		}
+
+		return template.HTML(buf.String()), nil
+	}
+}
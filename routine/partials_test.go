@@ -0,0 +1,85 @@
+package routine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildPartials(t *testing.T) {
+	t.Run("partials and base templates are shared", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("templates", "base.tmpl"):                     `{{ template "partials/header" . }}|{{ partial "nav/links" (dict "Link" "/a/") }}|{{ template "base_footer.tmpl" . }}|{{ .Content }}`,
+			filepath.Join("templates", "partials", "header.tmpl"):       "<h1>{{ .Title }}</h1>",
+			filepath.Join("templates", "partials", "nav", "links.html"): `<a href="{{ .Link }}">a</a>`,
+			filepath.Join("templates", "base_footer.tmpl"):              "footer",
+			filepath.Join("routes", "index.tmpl"):                       "---\ntitle: home\n---\n{{ partial \"partials/header\" (dict \"Title\" \"inner\") }}",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		index, _ := os.ReadFile(filepath.Join(dir, "build", "index.html"))
+		expected := `<h1>home</h1>|<a href="/a/">a</a>|footer|<h1>inner</h1>`
+		if string(index) != expected {
+			t.Logf("expected %v, received %v", expected, string(index))
+			t.Fail()
+		}
+	})
+
+	t.Run("name collisions are reported", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("templates", "partials", "header.tmpl"): `{{ define "logo" }}a{{ end }}`,
+			filepath.Join("templates", "partials", "footer.tmpl"): `{{ define "logo" }}b{{ end }}`,
+			filepath.Join("routes", "index.html"):                 "home",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err == nil || !strings.Contains(err.Error(), "`logo`") {
+			t.Logf("expected collision error, received %v", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("layouts may not redefine a partial", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("templates", "base.tmpl"):               `{{ define "partials/header" }}b{{ end }}{{ .Content }}`,
+			filepath.Join("templates", "partials", "header.tmpl"): "a",
+			filepath.Join("routes", "index.html"):                 "home",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err == nil || !strings.Contains(err.Error(), "partials/header") {
+			t.Logf("expected collision error, received %v", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("errors in partials name the partial", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("templates", "base.tmpl"):               `{{ template "partials/header" . }}`,
+			filepath.Join("templates", "partials", "header.tmpl"): "\n{{ div 1 0 }}",
+			filepath.Join("routes", "index.html"):                 "home",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+
+		path, line, _ := locate(err)
+		if filepath.Base(path) != "header.tmpl" || line != 2 {
+			t.Logf("expected location of partial, received %v:%v", path, line)
+			t.Fail()
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package routine
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jmkng/onyx/config"
+)
+
+const (
+	// Front matter key replacing the last segment of the link to a resource.
+	SlugKey = "slug"
+	// Front matter key replacing the entire link to a resource.
+	UrlKey = "url"
+)
+
+// permalinkToken matches a placeholder in a permalink pattern, such as `:year`.
+var permalinkToken = regexp.MustCompile(`:[a-z]+`)
+
+// permalink will return the destination of a resource, relative to the output
+// directory. The `url` front matter key takes precedence, followed by the pattern
+// configured for the group of the resource in config.State.Permalinks, and then the
+// `slug` front matter key. If none apply, the default destination is returned.
+func permalink(root string, res resource) (string, error) {
+	output := filepath.Join(root, Output())
+
+	fallback, err := diff(output, res.destination)
+	if err != nil {
+		return "", err
+	}
+
+	if value, ok := res.data[UrlKey]; ok {
+		url, ok := value.(string)
+		if !ok || strings.TrimSpace(url) == "" {
+			return "", fmt.Errorf("front matter key `%v` must be a string in resource: %v", UrlKey, res.path)
+		}
+
+		return permalinkFile(url), nil
+	}
+
+	name := strings.TrimSuffix(filepath.Base(res.path), filepath.Ext(res.path))
+
+	// listings keep their location, because they describe a directory, and the
+	// not found page must stay where hosts expect it
+	if name == "index" || fallback == NotFoundName+".html" {
+		return fallback, nil
+	}
+
+	slug, err := res.slug()
+	if err != nil {
+		return "", err
+	}
+
+	pattern, ok := config.State.Permalinks[res.group]
+	if ok && res.group != "" {
+		expanded, err := expand(root, pattern, res, slug)
+		if err != nil {
+			return "", err
+		}
+
+		return permalinkFile(expanded), nil
+	}
+
+	if _, ok := res.data[SlugKey]; ok {
+		return filepath.Join(filepath.Dir(filepath.Dir(fallback)), slug, "index.html"), nil
+	}
+
+	return fallback, nil
+}
+
+// slug will return the `slug` front matter key of a resource, or the name of
+// its file if the key is missing.
+func (res resource) slug() (string, error) {
+	value, ok := res.data[SlugKey]
+	if !ok {
+		return strings.TrimSuffix(filepath.Base(res.path), filepath.Ext(res.path)), nil
+	}
+
+	slug, ok := value.(string)
+	if !ok || slug == "" || strings.ContainsAny(slug, `/\`) {
+		return "", fmt.Errorf("front matter key `%v` must be a single path segment in resource: %v", SlugKey, res.path)
+	}
+
+	return slug, nil
+}
+
+// expand will replace every placeholder in a permalink pattern with its value
+// for the given resource. Recognized placeholders are `:year`, `:month`, `:day`,
+// `:slug`, `:filename` and `:section`.
+func expand(root, pattern string, res resource, slug string) (string, error) {
+	var err error
+
+	result := permalinkToken.ReplaceAllStringFunc(pattern, func(token string) string {
+		switch token {
+		case ":year", ":month", ":day":
+			date, parseErr := parseDate(res.date)
+			if parseErr != nil {
+				err = fmt.Errorf("permalink `%v` requires a date in resource: %v", pattern, res.path)
+				return ""
+			}
+
+			switch token {
+			case ":year":
+				return date.Format("2006")
+			case ":month":
+				return date.Format("01")
+			default:
+				return date.Format("02")
+			}
+		case ":slug":
+			return slug
+		case ":filename":
+			return strings.TrimSuffix(filepath.Base(res.path), filepath.Ext(res.path))
+		case ":section":
+			return section(root, res.path)
+		default:
+			err = fmt.Errorf("unrecognized placeholder `%v` in permalink `%v`", token, pattern)
+			return ""
+		}
+	})
+
+	return result, err
+}
+
+// section will return the first directory below routes that contains the file at
+// path, or an empty string if it is not in a directory.
+func section(root, file string) string {
+	rel, err := diff(filepath.Join(root, "routes"), file)
+	if err != nil {
+		return ""
+	}
+
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	if len(segments) < 2 {
+		return ""
+	}
+
+	return segments[0]
+}
+
+// permalinkFile will convert a link to a file path relative to the output
+// directory. Links without an extension describe a directory, and are given
+// an `index.html` file.
+func permalinkFile(link string) string {
+	clean := path.Clean("/" + link)
+
+	if strings.HasSuffix(link, "/") || path.Ext(clean) == "" {
+		clean = path.Join(clean, "index.html")
+	}
+
+	return filepath.FromSlash(strings.TrimPrefix(clean, "/"))
+}
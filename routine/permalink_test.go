@@ -0,0 +1,98 @@
+package routine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmkng/onyx/config"
+)
+
+func TestPermalinkFile(t *testing.T) {
+	cases := map[string]string{
+		"/2023/01/post/": filepath.Join("2023", "01", "post", "index.html"),
+		"/docs/intro":    filepath.Join("docs", "intro", "index.html"),
+		"/old/page.html": filepath.Join("old", "page.html"),
+		"/../escape/":    filepath.Join("escape", "index.html"),
+		"/":              "index.html",
+	}
+
+	for link, expected := range cases {
+		result := permalinkFile(link)
+		if result != expected {
+			t.Logf("expected %v for %v, received %v", expected, link, result)
+			t.Fail()
+		}
+	}
+}
+
+func TestBuildPermalinks(t *testing.T) {
+	t.Run("patterns and front matter overrides control output and links", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName:                            "permalinks:\n  blog: /:year/:month/:slug/\n  docs: /:section/:filename.html\n",
+			filepath.Join("routes", "index.tmpl"):          "{{ range .Blog }}{{ .Link }};{{ end }}{{ range .Docs }}{{ .Link }};{{ end }}",
+			filepath.Join("routes", "blog", "one.md"):      "---\ndate: 2023-04-05\nslug: first-post\n---\none",
+			filepath.Join("routes", "docs", "intro.md"):    "intro",
+			filepath.Join("routes", "pages", "about.md"):   "---\nslug: about-us\n---\nabout",
+			filepath.Join("routes", "pages", "contact.md"): "---\nurl: /contact.php\n---\ncontact",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		expected := []string{
+			filepath.Join("2023", "04", "first-post", "index.html"),
+			filepath.Join("docs", "intro.html"),
+			filepath.Join("pages", "about-us", "index.html"),
+			"contact.php",
+		}
+
+		for _, v := range expected {
+			if !exists(filepath.Join(dir, "build", v)) {
+				t.Logf("expected output at %v", v)
+				t.Fail()
+			}
+		}
+
+		index, _ := os.ReadFile(filepath.Join(dir, "build", "index.html"))
+		if string(index) != "/2023/04/first-post/index.html;/docs/intro.html;" {
+			t.Logf("expected links to follow permalinks, received %v", string(index))
+			t.Fail()
+		}
+	})
+
+	t.Run("date placeholders require a date", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName:                       "permalinks:\n  blog: /:year/:slug/\n",
+			filepath.Join("routes", "blog", "one.md"): "one",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err == nil {
+			t.Log("expected error for missing date")
+			t.Fail()
+		}
+	})
+
+	t.Run("resources may not share a link", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("routes", "blog", "one.md"): "---\nslug: same\n---\none",
+			filepath.Join("routes", "blog", "two.md"): "---\nslug: same\n---\ntwo",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err == nil {
+			t.Log("expected error for shared link")
+			t.Fail()
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package routine
+
+import (
+	"fmt"
+	"time"
+)
+
+// ExpiryKey is the front matter key holding the date after which a resource is
+// no longer published.
+const ExpiryKey = "expiryDate"
+
+// withhold will return a description of why a resource should be left out of the
+// build at the given time, or an empty string if it should be published. Drafts
+// and resources dated in the future are only published when requested with the
+// `--drafts` and `--future` flags, and expired resources are never published.
+func (routine *Build) withhold(res resource, now time.Time) (string, error) {
+	if res.isDraft() && !routine.drafts {
+		return "resource is a draft", nil
+	}
+
+	if res.date != "" && !routine.future {
+		date, err := parseDate(res.date)
+		if err != nil {
+			return "", fmt.Errorf("invalid date in resource: %v", res.path)
+		}
+
+		if date.After(now) {
+			return fmt.Sprintf("resource is scheduled for %v", res.date), nil
+		}
+	}
+
+	if value, ok := res.data[ExpiryKey]; ok {
+		expiry, err := dateString(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid `%v` in resource: %v\n%v", ExpiryKey, res.path, err)
+		}
+
+		date, err := parseDate(expiry)
+		if err != nil {
+			return "", fmt.Errorf("invalid `%v` in resource: %v", ExpiryKey, res.path)
+		}
+
+		if !date.After(now) {
+			return fmt.Sprintf("resource expired on %v", expiry), nil
+		}
+	}
+
+	return "", nil
+}
+
+// isDraft will return true if the resource is marked as a draft in front matter.
+func (res resource) isDraft() bool {
+	draft, ok := res.data["draft"].(bool)
+
+	return ok && draft
+}
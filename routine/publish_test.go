@@ -0,0 +1,64 @@
+package routine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithhold(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("published resource is not held back", func(t *testing.T) {
+		res := resource{
+			date: "2022-05-01",
+			data: map[string]any{ExpiryKey: "2022-07-01"},
+		}
+
+		reason, err := (&Build{}).withhold(res, now)
+		if err != nil || reason != "" {
+			t.Logf("unexpected reason: %v", reason)
+			t.Fail()
+		}
+	})
+
+	t.Run("future resource is held back unless requested", func(t *testing.T) {
+		res := resource{
+			date: "2022-06-02",
+		}
+
+		reason, err := (&Build{}).withhold(res, now)
+		if err != nil || reason == "" {
+			t.Log("expected future resource to be held back")
+			t.Fail()
+		}
+
+		reason, err = (&Build{future: true}).withhold(res, now)
+		if err != nil || reason != "" {
+			t.Log("expected future resource to be published with --future")
+			t.Fail()
+		}
+	})
+
+	t.Run("expired resource is held back", func(t *testing.T) {
+		res := resource{
+			data: map[string]any{ExpiryKey: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)},
+		}
+
+		reason, err := (&Build{future: true, drafts: true}).withhold(res, now)
+		if err != nil || reason == "" {
+			t.Log("expected expired resource to be held back")
+			t.Fail()
+		}
+	})
+
+	t.Run("invalid expiry date returns an error", func(t *testing.T) {
+		res := resource{
+			data: map[string]any{ExpiryKey: "soon"},
+		}
+
+		_, err := (&Build{}).withhold(res, now)
+		if err == nil {
+			t.Fail()
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/jmkng/onyx/config"
+	"github.com/jmkng/onyx/convert/md"
 )
 
 const (
@@ -13,6 +14,10 @@ const (
 	DefFilePerm fs.FileMode = 0644
 	// Default permission set for new directories.
 	DefDirPerm fs.FileMode = 0755
+	// Name of the route rendered as the not found page, such as `routes/404.md`.
+	NotFoundName = "404"
+	// Link to the not found page, which is written to the root of the output directory.
+	NotFoundLink = "/" + NotFoundName + ".html"
 )
 
 // WdOrPanic will return the working directory, or panic if os.Getwd() fails.
@@ -45,6 +50,11 @@ func Setup(path string) error {
 		return fmt.Errorf("configuration file `%v` is malformed", configPath)
 	}
 
+	err = md.Configure(config.State.Markdown)
+	if err != nil {
+		return fmt.Errorf("configuration file `%v` is malformed\n%v", configPath, err)
+	}
+
 	return nil
 }
 
@@ -52,3 +62,13 @@ func Setup(path string) error {
 func IsVerbose(arg bool) bool {
 	return arg || config.State.Verbose
 }
+
+// Output will return the name of the output directory, which is config.State.Output
+// or "build" if no output directory is configured.
+func Output() string {
+	if config.State.Output != "" {
+		return config.State.Output
+	}
+
+	return "build"
+}
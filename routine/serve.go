@@ -3,9 +3,13 @@ package routine
 import (
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"path/filepath"
+	"strings"
+
+	"github.com/jmkng/onyx/track"
 )
 
 func NewServe() *Serve {
@@ -16,6 +20,8 @@ func NewServe() *Serve {
 	routine.fs.StringVar(&routine.path, "path", WdOrPanic(), "Path to the project being served.")
 	routine.fs.IntVar(&routine.port, "port", 3883, "Port used to host the site.")
 	routine.fs.BoolVar(&routine.verbose, "verbose", false, "Display more detailed information")
+	routine.fs.BoolVar(&routine.drafts, "drafts", false, "Include resources marked as drafts.")
+	routine.fs.BoolVar(&routine.future, "future", false, "Include resources with a date in the future.")
 
 	return routine
 }
@@ -25,6 +31,9 @@ type Serve struct {
 	path    string
 	port    int
 	verbose bool
+	drafts  bool
+	future  bool
+	builder *Build
 }
 
 func (routine *Serve) Name() string {
@@ -66,15 +75,59 @@ func (routine *Serve) Execute() error {
 		count++
 	}
 
-	fmt.Printf("serving on http://localhost:%v\n", routine.port)
+	builder := &Build{
+		path:    routine.path,
+		verbose: routine.verbose,
+		drafts:  routine.drafts,
+		future:  routine.future,
+	}
+
+	routine.builder = builder
+
+	events := newHub()
+
+	rebuild := func() error {
+		err := builder.build()
+		track.Flush()
+
+		if err != nil {
+			fmt.Printf("build failed\n%v\n", err)
+			events.fail(err)
+		}
+
+		return err
+	}
 
-	http.Handle("/", http.FileServer(
-		http.Dir(
-			filepath.Join(routine.path, "build"),
-		),
-	))
+	// failed is true while the most recent build has failed, in which case the
+	// next successful build must reload the page instead of only the stylesheets.
+	failed := rebuild() != nil
+
+	go newWatcher(routine.path).run(nil, func(changed []string) {
+		if IsVerbose(routine.verbose) {
+			fmt.Printf("detected %v changed file(s), rebuilding\n", len(changed))
+		}
+
+		recovered := failed
+
+		failed = rebuild() != nil
+		if failed {
+			return
+		}
+
+		if isStylesheetChange(changed) && !recovered {
+			events.succeed(message{event: "css", data: "{}"})
+		} else {
+			events.succeed(message{event: "reload", data: "{}"})
+		}
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle(EventsPath, events)
+	mux.HandleFunc("/", routine.handler)
+
+	fmt.Printf("serving on http://localhost:%v\n", routine.port)
 
-	err = http.ListenAndServe(":"+fmt.Sprint(routine.port), nil)
+	err = http.ListenAndServe(":"+fmt.Sprint(routine.port), mux)
 	if err != nil {
 		return fmt.Errorf("failed to host server on http://localhost:%v", routine.port)
 	}
@@ -82,37 +135,61 @@ func (routine *Serve) Execute() error {
 	return nil
 }
 
-// func (routine *Serve) handler(w http.ResponseWriter, req *http.Request) {
-// 	url := req.URL
-//
-// 	var output string
-// 	if config.State.Output != "" {
-// 		output = config.State.Output
-// 	} else {
-// 		output = "build"
-// 	}
-//
-// 	var request string
-// 	ext := filepath.Ext(url.Path)
-// 	if ext != "" {
-// 		request = filepath.Join(routine.path, output, url.Path)
-// 	} else {
-// 		request = filepath.Join(routine.path, output, url.Path, "index.html")
-// 	}
-//
-// 	_, err := os.Stat(request)
-// 	if err != nil {
-// 		fmt.Fprint(w, "404")
-// 		return
-// 	}
-//
-// 	file, err := os.ReadFile(request)
-// 	if err != nil {
-// 		fmt.Fprint(w, "401")
-// 		return
-// 	}
-//
-// 	w.WriteHeader(http.StatusOK)
-//
-// 	fmt.Fprint(w, string(file))
-// }
+// handler will serve files from the output directory. HTML documents are served
+// with the reload script injected, and nothing is cached by the browser. Aliases
+// are answered with a permanent redirect.
+func (routine *Serve) handler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if to, ok := routine.builder.redirect(req.URL.Path); ok {
+		http.Redirect(w, req, to, http.StatusMovedPermanently)
+		return
+	}
+
+	request := req.URL.Path
+	if strings.HasSuffix(request, "/") {
+		request += "index.html"
+	}
+
+	root := http.Dir(routine.builder.built())
+
+	file, err := root.Open(request)
+	if err != nil {
+		routine.notFound(w, req)
+		return
+	}
+	defer file.Close()
+
+	if filepath.Ext(request) != ".html" {
+		http.FileServer(root).ServeHTTP(w, req)
+		return
+	}
+
+	routine.document(w, file, http.StatusOK)
+}
+
+// notFound will respond with the not found page of the project, or a plain
+// message if the project has none.
+func (routine *Serve) notFound(w http.ResponseWriter, req *http.Request) {
+	file, err := http.Dir(routine.builder.built()).Open(NotFoundLink)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	defer file.Close()
+
+	routine.document(w, file, http.StatusNotFound)
+}
+
+// document will respond with an HTML document with the reload script injected.
+func (routine *Serve) document(w http.ResponseWriter, file http.File, status int) {
+	document, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "unable to read file", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(inject(document))
+}
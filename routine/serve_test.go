@@ -0,0 +1,113 @@
+package routine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServeNotFound(t *testing.T) {
+	t.Run("missing paths receive the not found page", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("routes", "index.html"): "<body>home</body>",
+			filepath.Join("routes", "404.md"):     "missing",
+		})
+
+		builder := &Build{path: dir}
+
+		err := builder.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if !exists(filepath.Join(dir, "build", "404.html")) {
+			t.Log("expected not found page at root of output directory")
+			t.Fail()
+		}
+
+		serve := &Serve{path: dir, builder: builder}
+
+		for _, request := range []string{"/missing/", "/missing.css"} {
+			recorder := httptest.NewRecorder()
+			serve.handler(recorder, httptest.NewRequest(http.MethodGet, request, nil))
+
+			if recorder.Code != http.StatusNotFound || !strings.Contains(recorder.Body.String(), "missing") {
+				t.Logf("expected not found page for %v, received %v %v", request, recorder.Code, recorder.Body.String())
+				t.Fail()
+			}
+		}
+
+		recorder := httptest.NewRecorder()
+		serve.handler(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if recorder.Code != http.StatusOK {
+			t.Logf("expected existing page to be served, received %v", recorder.Code)
+			t.Fail()
+		}
+	})
+
+	t.Run("projects without a not found page receive a plain message", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("routes", "index.html"): "home",
+		})
+
+		builder := &Build{path: dir}
+
+		err := builder.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		serve := &Serve{path: dir, builder: builder}
+
+		recorder := httptest.NewRecorder()
+		serve.handler(recorder, httptest.NewRequest(http.MethodGet, "/missing/", nil))
+
+		if recorder.Code != http.StatusNotFound {
+			t.Logf("expected not found status, received %v", recorder.Code)
+			t.Fail()
+		}
+	})
+}
+
+func TestServeDuringRebuild(t *testing.T) {
+	dir := project(t, map[string]string{
+		filepath.Join("routes", "index.html"): "home",
+	})
+
+	builder := &Build{path: dir}
+
+	err := builder.build()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	serve := &Serve{path: dir, builder: builder}
+
+	done := make(chan error)
+	go func() {
+		done <- builder.build()
+	}()
+
+	// requests read the output directory while the rebuild replaces config.State
+	for {
+		recorder := httptest.NewRecorder()
+		serve.handler(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		select {
+		case err = <-done:
+			if err != nil {
+				t.Log(err)
+				t.Fail()
+			}
+
+			return
+		default:
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package routine
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/jmkng/onyx/convert/md"
+)
+
+const (
+	// Directory below templates holding shortcodes, which are named by file.
+	ShortcodesDir = "shortcodes"
+
+	shortcodeOpen  = "{{<"
+	shortcodeClose = ">}}"
+)
+
+// Shortcode is the context of a shortcode template, which is called from markdown
+// with `{{< name key="value" >}}`, or `{{< name >}}inner{{< /name >}}` when paired.
+type Shortcode struct {
+	// Name is the name of the shortcode.
+	Name string
+	// Params holds the named arguments, such as `src` in `{{< figure src="x.png" >}}`.
+	Params map[string]string
+	// Positional holds the arguments that are not named, in order.
+	Positional []string
+	// Inner holds the content between a paired shortcode, converted from markdown.
+	Inner template.HTML
+	// Page holds the front matter of the resource calling the shortcode.
+	Page map[string]any
+}
+
+// Get will return a named argument when given a string, or a positional argument
+// when given a number, such as `{{ .Get "src" }}` or `{{ .Get 0 }}`. An empty
+// string is returned if the argument is missing.
+func (s *Shortcode) Get(key any) string {
+	if index, ok := toInt(key); ok {
+		if index < 0 || index >= len(s.Positional) {
+			return ""
+		}
+
+		return s.Positional[index]
+	}
+
+	return s.Params[toString(key)]
+}
+
+// shortcodeNode is a piece of markdown, which is either text or a shortcode.
+type shortcodeNode struct {
+	text   string
+	name   string
+	args   string
+	line   int
+	paired bool
+	inner  []*shortcodeNode
+}
+
+// shortcodeFrame is a shortcode that may still be closed while parsing.
+type shortcodeFrame struct {
+	node     *shortcodeNode
+	children []*shortcodeNode
+}
+
+// markdown will convert markdown to HTML, expanding every shortcode found in body.
+// Shortcodes are replaced with placeholders before conversion and with the output
+// of their templates after, so that their output is never escaped. The path and
+// line where body begins are used to describe errors, and page holds the front
+// matter of the resource. The headings of the resource are returned along with
+// the HTML, excluding those in the content of paired shortcodes.
+func markdown(root, path, body string, line int, page map[string]any) (string, []md.Heading, error) {
+	nodes, err := parseShortcodes(path, body, line)
+	if err != nil {
+		return "", nil, err
+	}
+
+	expander := &shortcodeExpander{
+		root:         root,
+		path:         path,
+		page:         page,
+		document:     md.NewDocument(),
+		replacements: make(map[string]string),
+	}
+
+	return expander.convert(nodes)
+}
+
+// shortcodeExpander holds the state of expanding the shortcodes in a resource.
+type shortcodeExpander struct {
+	root         string
+	path         string
+	page         map[string]any
+	document     *md.Document
+	replacements map[string]string
+	templates    map[string]*template.Template
+}
+
+// convert will convert nodes to HTML, expanding each shortcode, and return the
+// headings found in the nodes.
+func (e *shortcodeExpander) convert(nodes []*shortcodeNode) (string, []md.Heading, error) {
+	var source strings.Builder
+	var placeholders []string
+
+	for _, node := range nodes {
+		if node.name == "" {
+			source.WriteString(node.text)
+			continue
+		}
+
+		output, err := e.expand(node)
+		if err != nil {
+			return "", nil, err
+		}
+
+		placeholder := md.Placeholder(len(e.replacements))
+		e.replacements[placeholder] = output
+		placeholders = append(placeholders, placeholder)
+
+		source.WriteString(placeholder)
+	}
+
+	var buf bytes.Buffer
+
+	headings, err := e.document.Convert([]byte(source.String()), &buf)
+	if err != nil {
+		return "", nil, err
+	}
+
+	result := buf.String()
+
+	for _, placeholder := range placeholders {
+		output := e.replacements[placeholder]
+
+		// a shortcode on its own line is placed in a paragraph by goldmark
+		result = strings.ReplaceAll(result, "<p>"+placeholder+"</p>", output)
+		result = strings.ReplaceAll(result, placeholder, output)
+	}
+
+	return result, headings, nil
+}
+
+// expand will execute the template of a shortcode.
+func (e *shortcodeExpander) expand(node *shortcodeNode) (string, error) {
+	tmpl, file, err := e.template(node)
+	if err != nil {
+		return "", err
+	}
+
+	context := &Shortcode{
+		Name:   node.name,
+		Params: make(map[string]string),
+		Page:   e.page,
+	}
+
+	params, positional, err := shortcodeArgs(node.args)
+	if err != nil {
+		return "", &sourceError{path: e.path, line: node.line, err: fmt.Errorf("malformed arguments in shortcode `%v`\n%v", node.name, err)}
+	}
+
+	context.Params = params
+	context.Positional = positional
+
+	if node.paired {
+		inner, _, err := e.convert(node.inner)
+		if err != nil {
+			return "", err
+		}
+
+		context.Inner = template.HTML(strings.TrimSpace(inner))
+	}
+
+	var buf bytes.Buffer
+
+	err = tmpl.Execute(&buf, context)
+	if err != nil {
+		wrapped := fmt.Errorf("encountered a problem while executing shortcode `%v` in resource: %v\n%v", node.name, e.path, err)
+
+		return "", templateError(wrapped, []string{file}, e.path, 0)
+	}
+
+	return buf.String(), nil
+}
+
+// template will return the parsed template of a shortcode and the path to its file.
+func (e *shortcodeExpander) template(node *shortcodeNode) (*template.Template, string, error) {
+	toShortcodes := filepath.Join(e.root, "templates", ShortcodesDir)
+
+	if e.templates == nil {
+		e.templates = make(map[string]*template.Template)
+	}
+
+	for _, ext := range []string{".tmpl", ".html"} {
+		file := filepath.Join(toShortcodes, node.name+ext)
+
+		if tmpl, ok := e.templates[file]; ok {
+			return tmpl, file, nil
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		tmpl, err := template.New(ShortcodesDir + "/" + node.name).Funcs(funcs()).Parse(string(data))
+		if err != nil {
+			return nil, "", templateError(fmt.Errorf("failed to parse shortcode\n%v", err), []string{file}, file, 0)
+		}
+
+		e.templates[file] = tmpl
+
+		return tmpl, file, nil
+	}
+
+	return nil, "", &sourceError{
+		path: e.path,
+		line: node.line,
+		err:  fmt.Errorf("shortcode `%v` does not exist in %v", node.name, toShortcodes),
+	}
+}
+
+// parseShortcodes will split markdown into text and shortcodes. A shortcode with
+// a matching closing tag is paired, and holds the nodes between the tags. A
+// shortcode may be written literally by wrapping its contents in a comment, such
+// as `{{</* name */>}}`.
+func parseShortcodes(path, body string, line int) ([]*shortcodeNode, error) {
+	root := &shortcodeFrame{}
+	stack := []*shortcodeFrame{root}
+
+	top := func() *shortcodeFrame {
+		return stack[len(stack)-1]
+	}
+
+	// unclosed shortcodes are self closing, and the nodes after them belong to
+	// their parent
+	flatten := func(frame *shortcodeFrame) {
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, frame.node)
+		parent.children = append(parent.children, frame.children...)
+	}
+
+	text := func(s string) {
+		if s != "" {
+			frame := top()
+			frame.children = append(frame.children, &shortcodeNode{text: s})
+		}
+	}
+
+	rest := body
+	offset := 0
+
+	for {
+		start := strings.Index(rest, shortcodeOpen)
+		if start == -1 {
+			text(rest)
+			break
+		}
+
+		end := shortcodeEnd(rest, start+len(shortcodeOpen))
+		tagLine := line + strings.Count(body[:offset+start], "\n")
+
+		if end == -1 {
+			return nil, &sourceError{path: path, line: tagLine, err: fmt.Errorf("shortcode is missing a closing `%v`", shortcodeClose)}
+		}
+
+		text(rest[:start])
+
+		tag := strings.TrimSpace(rest[start+len(shortcodeOpen) : end])
+
+		consumed := end + len(shortcodeClose)
+		offset += consumed
+		source := rest[start:consumed]
+		rest = rest[consumed:]
+
+		switch {
+		case strings.HasPrefix(tag, "/*") && strings.HasSuffix(tag, "*/"):
+			literal := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(tag, "/*"), "*/"))
+			text(shortcodeOpen + " " + literal + " " + shortcodeClose)
+		case strings.HasPrefix(tag, "/"):
+			name := strings.TrimSpace(strings.TrimPrefix(tag, "/"))
+
+			index := -1
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].node.name == name {
+					index = i
+					break
+				}
+			}
+
+			if index == -1 {
+				return nil, &sourceError{path: path, line: tagLine, err: fmt.Errorf("closing shortcode `%v` was never opened", source)}
+			}
+
+			for len(stack)-1 > index {
+				frame := top()
+				stack = stack[:len(stack)-1]
+				flatten(frame)
+			}
+
+			frame := top()
+			stack = stack[:len(stack)-1]
+
+			frame.node.paired = true
+			frame.node.inner = frame.children
+
+			parent := top()
+			parent.children = append(parent.children, frame.node)
+		default:
+			name, args := tag, ""
+			if index := strings.IndexFunc(tag, unicode.IsSpace); index != -1 {
+				name, args = tag[:index], tag[index:]
+			}
+
+			if name == "" {
+				return nil, &sourceError{path: path, line: tagLine, err: fmt.Errorf("shortcode is missing a name: %v", source)}
+			}
+
+			stack = append(stack, &shortcodeFrame{
+				node: &shortcodeNode{name: name, args: args, line: tagLine},
+			})
+		}
+	}
+
+	for len(stack) > 1 {
+		frame := top()
+		stack = stack[:len(stack)-1]
+		flatten(frame)
+	}
+
+	return root.children, nil
+}
+
+// shortcodeEnd will return the index of the closing delimiter of a shortcode that
+// begins before from, ignoring delimiters in quoted arguments, or -1 if the
+// shortcode is never closed.
+func shortcodeEnd(s string, from int) int {
+	quoted := false
+
+	for i := from; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == '\n' && quoted:
+			return -1
+		case !quoted && strings.HasPrefix(s[i:], shortcodeClose):
+			return i
+		}
+	}
+
+	return -1
+}
+
+// shortcodeArgs will parse the arguments of a shortcode, which are named such as
+// `key="value"` or `key=value`, or positional such as `"value"` or `value`.
+func shortcodeArgs(args string) (map[string]string, []string, error) {
+	params := make(map[string]string)
+
+	var positional []string
+
+	rest := strings.TrimSpace(args)
+
+	for rest != "" {
+		key := ""
+
+		if index := strings.IndexAny(rest, "= \t\n\""); index > 0 && rest[index] == '=' {
+			key = rest[:index]
+			rest = rest[index+1:]
+		}
+
+		var value string
+
+		if strings.HasPrefix(rest, "\"") {
+			end := strings.Index(rest[1:], "\"")
+			if end == -1 {
+				return nil, nil, fmt.Errorf("argument is missing a closing quote: %v", rest)
+			}
+
+			value = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end == -1 {
+				end = len(rest)
+			}
+
+			value = rest[:end]
+			rest = rest[end:]
+		}
+
+		if key != "" {
+			params[key] = value
+		} else {
+			positional = append(positional, value)
+		}
+
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+
+	return params, positional, nil
+}
@@ -0,0 +1,123 @@
+package routine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShortcodeArgs(t *testing.T) {
+	params, positional, err := shortcodeArgs(` src="a b.png" width=300 "first" second `)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if params["src"] != "a b.png" || params["width"] != "300" {
+		t.Logf("received params %v", params)
+		t.Fail()
+	}
+
+	if len(positional) != 2 || positional[0] != "first" || positional[1] != "second" {
+		t.Logf("received positional %v", positional)
+		t.Fail()
+	}
+
+	_, _, err = shortcodeArgs(`src="a`)
+	if err == nil {
+		t.Log("expected error for missing quote")
+		t.Fail()
+	}
+}
+
+func TestParseShortcodes(t *testing.T) {
+	t.Run("paired and self closing shortcodes are recognized", func(t *testing.T) {
+		nodes, err := parseShortcodes("test.md", `a {{< one >}} b {{< two x=">}}" >}}c{{< one >}}{{< /two >}} d`, 1)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		var names []string
+		for _, v := range nodes {
+			if v.name != "" {
+				names = append(names, v.name)
+			}
+		}
+
+		if strings.Join(names, ",") != "one,two" || !nodes[3].paired || len(nodes[3].inner) != 2 {
+			t.Logf("received %v", names)
+			t.Fail()
+		}
+	})
+
+	t.Run("unopened closing shortcodes are reported with a line", func(t *testing.T) {
+		_, err := parseShortcodes("test.md", "a\n\n{{< /note >}}", 5)
+
+		_, line, _ := locate(err)
+		if line != 7 {
+			t.Logf("expected error on line 7, received %v", err)
+			t.Fail()
+		}
+	})
+}
+
+func TestBuildShortcodes(t *testing.T) {
+	t.Run("shortcodes expand in markdown without escaping", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("templates", "shortcodes", "figure.tmpl"): `<figure><img src="{{ .Get "src" }}"><figcaption>{{ .Get "caption" }}</figcaption></figure>`,
+			filepath.Join("templates", "shortcodes", "note.html"):   `<aside class="{{ .Get 0 }}">{{ .Inner }}</aside>`,
+			filepath.Join("templates", "shortcodes", "title.tmpl"):  `{{ .Page.Title }}`,
+			filepath.Join("routes", "post.md"): strings.Join([]string{
+				"---",
+				"title: Post",
+				"---",
+				`{{< figure src="x.png" caption="A & B" >}}`,
+				"",
+				`{{< note warning >}}**bold**{{< /note >}}`,
+				"",
+				`In {{< title >}} and {{</* figure */>}}.`,
+			}, "\n"),
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		post, _ := os.ReadFile(filepath.Join(dir, "build", "post", "index.html"))
+
+		expected := []string{
+			`<figure><img src="x.png"><figcaption>A &amp; B</figcaption></figure>`,
+			`<aside class="warning"><p><strong>bold</strong></p></aside>`,
+			`<p>In Post and {{&lt; figure &gt;}}.</p>`,
+		}
+
+		for _, v := range expected {
+			if !strings.Contains(string(post), v) {
+				t.Logf("expected %v, received %v", v, string(post))
+				t.Fail()
+			}
+		}
+	})
+
+	t.Run("missing shortcodes are reported with a line", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("routes", "post.md"): "---\ntitle: Post\n---\ntext\n{{< missing >}}",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+
+		path, line, _ := locate(err)
+		if filepath.Base(path) != "post.md" || line != 5 {
+			t.Logf("expected location of shortcode, received %v", err)
+			t.Fail()
+		}
+	})
+}
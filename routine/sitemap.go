@@ -0,0 +1,243 @@
+package routine
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmkng/onyx/config"
+	"github.com/jmkng/onyx/track"
+)
+
+const (
+	// Front matter key that excludes a resource from the sitemap when false.
+	SitemapKey = "sitemap"
+	// Front matter key holding the date a resource was last modified.
+	LastmodKey = "lastmod"
+	// Maximum number of URLs in a single sitemap, as defined by the protocol.
+	SitemapLimit = 50000
+
+	sitemapFile = "sitemap.xml"
+	robotsFile  = "robots.txt"
+	sitemapNs   = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+type sitemapUrl struct {
+	Loc     string `xml:"loc"`
+	Lastmod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapUrlset struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Urls    []sitemapUrl `xml:"url"`
+}
+
+type sitemapEntry struct {
+	Loc string `xml:"loc"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	Xmlns    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// sitemap will write a sitemap listing the link of every written resource, along
+// with a robots.txt that points at it. Nothing is written unless
+// config.State.BaseURL is set, because the sitemap protocol requires absolute URLs.
+func (routine *Build) sitemap(written []resource) error {
+	if config.State.BaseURL == "" {
+		if IsVerbose(routine.verbose) {
+			track.Log("sitemap and robots.txt are skipped because `baseURL` is not set")
+		}
+
+		return nil
+	}
+
+	output := filepath.Join(routine.path, Output())
+
+	if !config.State.Sitemap.Disabled {
+		var urls []sitemapUrl
+
+		for _, res := range written {
+			url, ok, err := sitemapLocation(res)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				urls = append(urls, url)
+			}
+		}
+
+		sort.Slice(urls, func(i, j int) bool {
+			return urls[i].Loc < urls[j].Loc
+		})
+
+		files, err := sitemapFiles(urls, SitemapLimit)
+		if err != nil {
+			return fmt.Errorf("unable to create sitemap\n%v", err)
+		}
+
+		for name, data := range files {
+			err = routine.write(filepath.Join(output, name), data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !config.State.Robots.Disabled {
+		err := routine.write(filepath.Join(output, robotsFile), []byte(robotsTxt()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sitemapLocation will return the sitemap entry for a resource, or false if the
+// resource opts out of the sitemap with front matter or is the not found page.
+// The last modified date is taken from the `lastmod` or `date` front matter keys,
+// or the file itself.
+func sitemapLocation(res resource) (sitemapUrl, bool, error) {
+	if res.link == NotFoundLink {
+		return sitemapUrl{}, false, nil
+	}
+
+	if value, ok := res.data[SitemapKey]; ok {
+		include, ok := value.(bool)
+		if !ok {
+			return sitemapUrl{}, false, fmt.Errorf("front matter key `%v` must be true or false in resource: %v", SitemapKey, res.path)
+		}
+
+		if !include {
+			return sitemapUrl{}, false, nil
+		}
+	}
+
+	url := sitemapUrl{Loc: absURL(res.link)}
+
+	date := res.date
+
+	if value, ok := res.data[LastmodKey]; ok {
+		asStr, err := dateString(value)
+		if err != nil {
+			return sitemapUrl{}, false, fmt.Errorf("front matter key `%v` must be a date in resource: %v", LastmodKey, res.path)
+		}
+
+		date = asStr
+	}
+
+	if date != "" {
+		parsed, err := parseDate(date)
+		if err != nil {
+			return sitemapUrl{}, false, fmt.Errorf("unable to parse date `%v` in resource: %v", date, res.path)
+		}
+
+		url.Lastmod = w3cDate(parsed)
+		return url, true, nil
+	}
+
+	// generated resources do not have a file
+	info, err := os.Stat(res.path)
+	if err == nil {
+		url.Lastmod = info.ModTime().UTC().Format(time.RFC3339)
+	}
+
+	return url, true, nil
+}
+
+// w3cDate will format a date as expected by the sitemap protocol, omitting the
+// time if it is midnight.
+func w3cDate(date time.Time) string {
+	hour, min, sec := date.Clock()
+	if hour == 0 && min == 0 && sec == 0 {
+		return date.Format(config.DateFmt)
+	}
+
+	return date.Format(time.RFC3339)
+}
+
+// sitemapFiles will return the contents of each sitemap file by name. A single
+// sitemap is returned when urls fit within limit, otherwise urls are split across
+// numbered sitemaps and `sitemap.xml` becomes an index of them.
+func sitemapFiles(urls []sitemapUrl, limit int) (map[string][]byte, error) {
+	result := make(map[string][]byte)
+
+	if len(urls) <= limit {
+		data, err := marshalXml(sitemapUrlset{Xmlns: sitemapNs, Urls: urls})
+		if err != nil {
+			return nil, err
+		}
+
+		result[sitemapFile] = data
+		return result, nil
+	}
+
+	index := sitemapIndex{Xmlns: sitemapNs}
+
+	for start, n := 0, 1; start < len(urls); start, n = start+limit, n+1 {
+		end := start + limit
+		if end > len(urls) {
+			end = len(urls)
+		}
+
+		data, err := marshalXml(sitemapUrlset{Xmlns: sitemapNs, Urls: urls[start:end]})
+		if err != nil {
+			return nil, err
+		}
+
+		name := fmt.Sprintf("sitemap-%v.xml", n)
+		result[name] = data
+
+		index.Sitemaps = append(index.Sitemaps, sitemapEntry{Loc: absURL("/" + name)})
+	}
+
+	data, err := marshalXml(index)
+	if err != nil {
+		return nil, err
+	}
+
+	result[sitemapFile] = data
+
+	return result, nil
+}
+
+// robotsTxt will return the contents of robots.txt from config.State.Robots,
+// pointing crawlers at the sitemap unless it is disabled.
+func robotsTxt() string {
+	var builder strings.Builder
+
+	agent := config.State.Robots.UserAgent
+	if agent == "" {
+		agent = "*"
+	}
+
+	fmt.Fprintf(&builder, "User-agent: %v\n", agent)
+
+	for _, v := range config.State.Robots.Allow {
+		fmt.Fprintf(&builder, "Allow: %v\n", v)
+	}
+
+	for _, v := range config.State.Robots.Disallow {
+		fmt.Fprintf(&builder, "Disallow: %v\n", v)
+	}
+
+	// an empty disallow rule permits everything
+	if len(config.State.Robots.Allow) == 0 && len(config.State.Robots.Disallow) == 0 {
+		builder.WriteString("Disallow:\n")
+	}
+
+	if !config.State.Sitemap.Disabled {
+		fmt.Fprintf(&builder, "\nSitemap: %v\n", absURL("/"+sitemapFile))
+	}
+
+	return builder.String()
+}
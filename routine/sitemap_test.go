@@ -0,0 +1,107 @@
+package routine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmkng/onyx/config"
+)
+
+func TestSitemapFiles(t *testing.T) {
+	urls := []sitemapUrl{{Loc: "a"}, {Loc: "b"}, {Loc: "c"}}
+
+	t.Run("urls within the limit are written to one sitemap", func(t *testing.T) {
+		files, err := sitemapFiles(urls, 3)
+		if err != nil || len(files) != 1 {
+			t.Logf("expected one file, received %v", len(files))
+			t.Fail()
+		}
+	})
+
+	t.Run("urls beyond the limit are split behind an index", func(t *testing.T) {
+		files, err := sitemapFiles(urls, 2)
+		if err != nil || len(files) != 3 {
+			t.Logf("expected three files, received %v", len(files))
+			t.FailNow()
+		}
+
+		index := string(files["sitemap.xml"])
+		if !strings.Contains(index, "<sitemapindex") || !strings.Contains(index, "sitemap-2.xml") {
+			t.Logf("expected sitemap index, received %v", index)
+			t.Fail()
+		}
+
+		if strings.Count(string(files["sitemap-2.xml"]), "<url>") != 1 {
+			t.Log("expected remaining url in second sitemap")
+			t.Fail()
+		}
+	})
+}
+
+func TestBuildSitemap(t *testing.T) {
+	t.Run("sitemap lists resources and robots.txt points at it", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName:                          "baseURL: https://example.com\nrobots:\n  disallow: [/private/]\n",
+			filepath.Join("routes", "index.html"):        "home",
+			filepath.Join("routes", "posts", "one.md"):   "---\ndate: 2023-01-01\nlastmod: 2023-03-01\n---\none",
+			filepath.Join("routes", "posts", "two.md"):   "---\ndate: 2023-02-01\n---\ntwo",
+			filepath.Join("routes", "posts", "three.md"): "---\nsitemap: false\n---\nthree",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		data, _ := os.ReadFile(filepath.Join(dir, "build", "sitemap.xml"))
+		sitemap := string(data)
+
+		expected := []string{
+			"<loc>https://example.com/index.html</loc>",
+			"<loc>https://example.com/posts/one/index.html</loc>\n    <lastmod>2023-03-01</lastmod>",
+			"<loc>https://example.com/posts/two/index.html</loc>\n    <lastmod>2023-02-01</lastmod>",
+		}
+
+		for _, v := range expected {
+			if !strings.Contains(sitemap, v) {
+				t.Logf("expected sitemap to contain %v, received %v", v, sitemap)
+				t.Fail()
+			}
+		}
+
+		if strings.Contains(sitemap, "three") {
+			t.Log("expected resource to opt out of sitemap")
+			t.Fail()
+		}
+
+		robots, _ := os.ReadFile(filepath.Join(dir, "build", "robots.txt"))
+		if string(robots) != "User-agent: *\nDisallow: /private/\n\nSitemap: https://example.com/sitemap.xml\n" {
+			t.Logf("received robots.txt %v", string(robots))
+			t.Fail()
+		}
+	})
+
+	t.Run("nothing is written without a base url", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			filepath.Join("routes", "index.html"): "home",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if exists(filepath.Join(dir, "build", "sitemap.xml")) || exists(filepath.Join(dir, "build", "robots.txt")) {
+			t.Log("expected sitemap and robots.txt to be skipped")
+			t.Fail()
+		}
+	})
+}
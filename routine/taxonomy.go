@@ -0,0 +1,216 @@
+package routine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jmkng/onyx/config"
+)
+
+const (
+	// Template used for the index page of a taxonomy when none is configured.
+	DefTaxonomyTemplate = "taxonomy.tmpl"
+	// Template used for the page of a term when none is configured.
+	DefTermTemplate = "term.tmpl"
+)
+
+// Term is a single value of a taxonomy, such as the tag `go`. Every taxonomy is
+// available to templates as a sorted list of terms through `.Taxonomies`, for
+// example `{{ range .Taxonomies.tags }}{{ .Name }}{{ end }}`.
+type Term struct {
+	// Name is the term as it was written in front matter.
+	Name string
+	// Slug is the name of the term as it appears in links.
+	Slug string
+	// Link is a link to the page listing every resource with this term.
+	Link string
+	// Pages holds every resource with this term, newest first.
+	Pages []map[string]any
+}
+
+// Count will return the number of resources with this term.
+func (t *Term) Count() int {
+	return len(t.Pages)
+}
+
+// classify will gather the terms of every taxonomy in config.State.Taxonomies
+// from the front matter of each resource. The terms of each taxonomy are returned
+// by taxonomy name, along with a generated resource for the index page of each
+// taxonomy and the page of each term.
+func (routine *Build) classify(renderable []resource) (map[string][]*Term, []resource, error) {
+	taxonomies := make(map[string][]*Term)
+
+	var generated []resource
+
+	for _, taxonomy := range config.State.Taxonomies {
+		if taxonomy.Name == "" {
+			return nil, nil, fmt.Errorf("taxonomy is missing a name in configuration file")
+		}
+
+		bySlug := make(map[string]*Term)
+
+		for _, res := range renderable {
+			names, err := terms(res, taxonomy.Name)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for _, name := range names {
+				slug := slugify(name)
+				if slug == "" {
+					continue
+				}
+
+				term, ok := bySlug[slug]
+				if !ok {
+					term = &Term{
+						Name: name,
+						Slug: slug,
+						Link: "/" + filepath.ToSlash(filepath.Join(slugify(taxonomy.Name), slug, "index.html")),
+					}
+
+					bySlug[slug] = term
+				}
+
+				term.Pages = append(term.Pages, res.member())
+			}
+		}
+
+		var list []*Term
+		for _, term := range bySlug {
+			sortByDate(term.Pages)
+			list = append(list, term)
+		}
+
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Slug < list[j].Slug
+		})
+
+		taxonomies[taxonomy.Name] = list
+
+		indexTemplate := routine.taxonomyTemplate(taxonomy.IndexTemplate, DefTaxonomyTemplate)
+		termTemplate := routine.taxonomyTemplate(taxonomy.TermTemplate, DefTermTemplate)
+
+		output := filepath.Join(routine.path, Output(), slugify(taxonomy.Name))
+
+		generated = append(generated, resource{
+			path:        fmt.Sprintf("taxonomy `%v`", taxonomy.Name),
+			destination: filepath.Join(output, "index.html"),
+			link:        "/" + filepath.ToSlash(filepath.Join(slugify(taxonomy.Name), "index.html")),
+			template:    indexTemplate,
+			data: map[string]any{
+				"title": taxonomy.Name,
+			},
+			context: map[string]any{
+				"Taxonomy": taxonomy.Name,
+				"Terms":    list,
+			},
+		})
+
+		for _, term := range list {
+			generated = append(generated, resource{
+				path:        fmt.Sprintf("term `%v` of taxonomy `%v`", term.Name, taxonomy.Name),
+				destination: filepath.Join(output, term.Slug, "index.html"),
+				link:        term.Link,
+				template:    termTemplate,
+				data: map[string]any{
+					"title": term.Name,
+				},
+				context: map[string]any{
+					"Taxonomy": taxonomy.Name,
+					"Term":     term,
+				},
+			})
+		}
+	}
+
+	return taxonomies, generated, nil
+}
+
+// taxonomyTemplate will return the configured template, or the default template
+// if none is configured and the default exists. An empty string is returned if
+// the page should only be rendered with the base template.
+func (routine *Build) taxonomyTemplate(configured, fallback string) string {
+	if configured != "" {
+		return configured
+	}
+
+	_, err := os.Stat(filepath.Join(routine.path, "templates", fallback))
+	if err != nil {
+		return ""
+	}
+
+	return fallback
+}
+
+// terms will return the terms of a taxonomy found in the front matter of a
+// resource, which may be a single string or a list of strings.
+func terms(res resource, taxonomy string) ([]string, error) {
+	value, ok := res.data[taxonomy]
+	if !ok {
+		return nil, nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []any:
+		var result []string
+
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("front matter key `%v` must be a list of strings in resource: %v", taxonomy, res.path)
+			}
+
+			result = append(result, name)
+		}
+
+		return result, nil
+	default:
+		return nil, fmt.Errorf("front matter key `%v` must be a list of strings in resource: %v", taxonomy, res.path)
+	}
+}
+
+// sortByDate will sort members of a listing by date, newest first. Members
+// missing a date sort last.
+func sortByDate(members []map[string]any) {
+	date := func(member map[string]any) time.Time {
+		asStr, _ := member["Date"].(string)
+		result, _ := parseDate(asStr)
+
+		return result
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		return date(members[i]).After(date(members[j]))
+	})
+}
+
+// slugify will convert a string to a form that is safe to use in a link, by
+// lowercasing letters and replacing every run of other characters with `-`.
+func slugify(s string) string {
+	var builder strings.Builder
+
+	dash := false
+
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+			dash = false
+			continue
+		}
+
+		if !dash && builder.Len() > 0 {
+			builder.WriteRune('-')
+			dash = true
+		}
+	}
+
+	return strings.TrimSuffix(builder.String(), "-")
+}
@@ -0,0 +1,106 @@
+package routine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmkng/onyx/config"
+)
+
+func TestSlugify(t *testing.T) {
+	t.Run("strings are lowercased and separated by dashes", func(t *testing.T) {
+		cases := map[string]string{
+			"Go":              "go",
+			"Web Development": "web-development",
+			"  C++ & Rust!  ": "c-rust",
+			"Über-Café":       "über-café",
+		}
+
+		for input, expected := range cases {
+			result := slugify(input)
+			if result != expected {
+				t.Logf("expected %v, received %v", expected, result)
+				t.Fail()
+			}
+		}
+	})
+}
+
+func TestTerms(t *testing.T) {
+	t.Run("single string and list are accepted", func(t *testing.T) {
+		single, err := terms(resource{data: map[string]any{"tags": "go"}}, "tags")
+		if err != nil || len(single) != 1 {
+			t.Fail()
+		}
+
+		list, err := terms(resource{data: map[string]any{"tags": []any{"go", "web"}}}, "tags")
+		if err != nil || len(list) != 2 {
+			t.Fail()
+		}
+	})
+
+	t.Run("other types return an error", func(t *testing.T) {
+		_, err := terms(resource{data: map[string]any{"tags": []any{3}}}, "tags")
+		if err == nil {
+			t.Fail()
+		}
+	})
+}
+
+func TestBuildTaxonomies(t *testing.T) {
+	t.Run("index and term pages are generated", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName:                        "taxonomies:\n  - name: tags\n",
+			filepath.Join("templates", "base.tmpl"):    "{{ range .Taxonomies.tags }}{{ .Slug }};{{ end }}{{ block \"main\" . }}{{ end }}",
+			filepath.Join("templates", "term.tmpl"):    "{{ define \"main\" }}|{{ range .Term.Pages }}{{ .Title }}{{ end }}{{ end }}",
+			filepath.Join("routes", "posts", "one.md"): "---\ntitle: one\ntags: [go, web]\n---\none",
+			filepath.Join("routes", "posts", "two.md"): "---\ntitle: two\ntags: go\n---\ntwo",
+			filepath.Join("routes", "about.md"):        "---\ntitle: about\n---\nabout",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		expected := map[string]string{
+			filepath.Join(dir, "build", "tags", "index.html"):        "go;web;",
+			filepath.Join(dir, "build", "tags", "web", "index.html"): "go;web;|one",
+			filepath.Join(dir, "build", "about", "index.html"):       "go;web;",
+		}
+
+		for path, content := range expected {
+			result, err := os.ReadFile(path)
+			if err != nil || string(result) != content {
+				t.Logf("expected %v in %v, received %v", content, path, string(result))
+				t.Fail()
+			}
+		}
+
+		result, err := os.ReadFile(filepath.Join(dir, "build", "tags", "go", "index.html"))
+		if err != nil || len(result) != len("go;web;|onetwo") {
+			t.Logf("unexpected term page: %v", string(result))
+			t.Fail()
+		}
+	})
+	t.Run("generated pages may not share a link with a route", func(t *testing.T) {
+		dir := project(t, map[string]string{
+			config.YamlLongName:                           "taxonomies:\n  - name: tags\n",
+			filepath.Join("routes", "tags", "index.html"): "hand written",
+			filepath.Join("routes", "posts", "one.md"):    "---\ntitle: one\ntags: go\n---\none",
+		})
+
+		routine := Build{path: dir}
+
+		err := routine.build()
+		if err == nil || !strings.Contains(err.Error(), "resources share the same link") {
+			t.Logf("expected error for shared link, received %v", err)
+			t.Fail()
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package routine
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/jmkng/onyx/config"
+	"github.com/jmkng/onyx/convert/md"
+)
+
+// TOC is the table of contents of a markdown resource, which is available to
+// templates as `.TOC`. Only headings between the configured start and end levels
+// are included.
+type TOC struct {
+	// Headings holds the top level headings, each holding the headings below it.
+	Headings []*TOCEntry
+	// HTML holds the headings rendered as nested lists, such as `{{ .TOC.HTML }}`.
+	HTML template.HTML
+}
+
+// TOCEntry is a heading in a table of contents.
+type TOCEntry struct {
+	// Level is the level of the heading, from 1 to 6.
+	Level int
+	// ID is the id of the heading, which is linked with `#{{ .ID }}`.
+	ID string
+	// Title is the text of the heading.
+	Title string
+	// Children holds the headings below this heading.
+	Children []*TOCEntry
+}
+
+// newTOC will create a table of contents from the headings of a resource, in the
+// order they are found. A heading belongs to the closest heading before it with
+// a lower level.
+func newTOC(headings []md.Heading) TOC {
+	start, end := config.State.Markdown.TOC.Levels()
+
+	var roots []*TOCEntry
+	var stack []*TOCEntry
+
+	for _, v := range headings {
+		if v.Level < start || v.Level > end {
+			continue
+		}
+
+		entry := &TOCEntry{Level: v.Level, ID: v.ID, Title: v.Title}
+
+		for len(stack) > 0 && stack[len(stack)-1].Level >= v.Level {
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) == 0 {
+			roots = append(roots, entry)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, entry)
+		}
+
+		stack = append(stack, entry)
+	}
+
+	result := TOC{Headings: roots}
+
+	if len(roots) > 0 {
+		var builder strings.Builder
+
+		builder.WriteString(`<nav class="toc">`)
+		writeTOC(&builder, roots)
+		builder.WriteString("</nav>")
+
+		result.HTML = template.HTML(builder.String())
+	}
+
+	return result
+}
+
+// writeTOC will write entries and their children as nested lists.
+func writeTOC(builder *strings.Builder, entries []*TOCEntry) {
+	builder.WriteString("<ul>")
+
+	for _, v := range entries {
+		builder.WriteString(`<li><a href="#`)
+		builder.WriteString(template.HTMLEscapeString(v.ID))
+		builder.WriteString(`">`)
+		builder.WriteString(template.HTMLEscapeString(v.Title))
+		builder.WriteString("</a>")
+
+		if len(v.Children) > 0 {
+			writeTOC(builder, v.Children)
+		}
+
+		builder.WriteString("</li>")
+	}
+
+	builder.WriteString("</ul>")
+}
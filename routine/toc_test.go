@@ -0,0 +1,57 @@
+package routine
+
+import (
+	"testing"
+
+	"github.com/jmkng/onyx/config"
+	"github.com/jmkng/onyx/convert/md"
+)
+
+func TestNewTOC(t *testing.T) {
+	headings := []md.Heading{
+		{Level: 1, ID: "title", Title: "Title"},
+		{Level: 2, ID: "install", Title: "Install"},
+		{Level: 3, ID: "linux", Title: "Linux"},
+		{Level: 4, ID: "arch", Title: "Arch"},
+		{Level: 3, ID: "mac", Title: "Mac & Co"},
+		{Level: 2, ID: "usage", Title: "Usage"},
+	}
+
+	t.Run("headings are nested between the default levels", func(t *testing.T) {
+		toc := newTOC(headings)
+
+		if len(toc.Headings) != 2 || len(toc.Headings[0].Children) != 2 || toc.Headings[0].Children[1].ID != "mac" {
+			t.Logf("received %+v", toc.Headings)
+			t.Fail()
+		}
+
+		expected := `<nav class="toc"><ul><li><a href="#install">Install</a><ul><li><a href="#linux">Linux</a></li>` +
+			`<li><a href="#mac">Mac &amp; Co</a></li></ul></li><li><a href="#usage">Usage</a></li></ul></nav>`
+
+		if string(toc.HTML) != expected {
+			t.Logf("expected %v, received %v", expected, toc.HTML)
+			t.Fail()
+		}
+	})
+
+	t.Run("levels are configurable", func(t *testing.T) {
+		config.State.Markdown.TOC = md.TOC{StartLevel: 3, EndLevel: 4}
+		defer func() { config.State.Markdown.TOC = md.TOC{} }()
+
+		toc := newTOC(headings)
+
+		if len(toc.Headings) != 2 || toc.Headings[0].ID != "linux" || toc.Headings[0].Children[0].ID != "arch" {
+			t.Logf("received %+v", toc.Headings)
+			t.Fail()
+		}
+	})
+
+	t.Run("no headings render no HTML", func(t *testing.T) {
+		toc := newTOC(nil)
+
+		if toc.HTML != "" {
+			t.Logf("received %v", toc.HTML)
+			t.Fail()
+		}
+	})
+}
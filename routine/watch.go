@@ -0,0 +1,162 @@
+package routine
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jmkng/onyx/config"
+)
+
+const (
+	// Time between each scan of the watched paths.
+	WatchInterval = 250 * time.Millisecond
+	// Time that must pass without a new change before a batch of changes is reported.
+	WatchDebounce = 500 * time.Millisecond
+)
+
+// stamp describes the state of a file at the time it was scanned.
+type stamp struct {
+	modified time.Time
+	size     int64
+}
+
+// watcher polls a set of files and directories for changes.
+type watcher struct {
+	paths []string
+	state map[string]stamp
+}
+
+// newWatcher creates a watcher for the source directories and configuration
+// files of the project at root.
+func newWatcher(root string) *watcher {
+	paths := []string{
+		filepath.Join(root, "routes"),
+		filepath.Join(root, "templates"),
+		filepath.Join(root, "static"),
+		filepath.Join(root, "data"),
+	}
+
+	for _, v := range config.Names {
+		paths = append(paths, filepath.Join(root, v))
+	}
+
+	w := &watcher{
+		paths: paths,
+	}
+
+	w.state = w.scan()
+
+	return w
+}
+
+// scan will walk every watched path and return the current state of each file.
+// Paths that do not exist are skipped.
+func (w *watcher) scan() map[string]stamp {
+	result := make(map[string]stamp)
+
+	for _, v := range w.paths {
+		filepath.WalkDir(v, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+
+			if d.IsDir() || !d.Type().IsRegular() {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+
+			result[path] = stamp{
+				modified: info.ModTime(),
+				size:     info.Size(),
+			}
+
+			return nil
+		})
+	}
+
+	return result
+}
+
+// changed will scan the watched paths and return every file that was created,
+// modified or removed since the previous scan.
+func (w *watcher) changed() []string {
+	next := w.scan()
+
+	var result []string
+
+	for path, now := range next {
+		before, ok := w.state[path]
+		if !ok || !before.modified.Equal(now.modified) || before.size != now.size {
+			result = append(result, path)
+		}
+	}
+
+	for path := range w.state {
+		if _, ok := next[path]; !ok {
+			result = append(result, path)
+		}
+	}
+
+	w.state = next
+
+	sort.Strings(result)
+
+	return result
+}
+
+// run will poll the watched paths until stop is closed. Changes are collected until
+// WatchDebounce passes without a new change, and are then passed to onChange as a
+// single batch, so a burst of saves from an editor only causes one call.
+func (w *watcher) run(stop <-chan struct{}, onChange func([]string)) {
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+
+	var pending []string
+	var last time.Time
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			changed := w.changed()
+			if len(changed) > 0 {
+				pending = merge(pending, changed)
+				last = now
+			}
+
+			if len(pending) > 0 && now.Sub(last) >= WatchDebounce {
+				batch := pending
+				pending = nil
+
+				onChange(batch)
+			}
+		}
+	}
+}
+
+// merge will return the sorted union of two sorted sets of paths.
+func merge(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+
+	var result []string
+
+	for _, v := range append(a, b...) {
+		if seen[v] {
+			continue
+		}
+
+		seen[v] = true
+		result = append(result, v)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
@@ -0,0 +1,131 @@
+package routine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmkng/onyx/config"
+)
+
+func TestWatcherChanged(t *testing.T) {
+	dir, err := config.CreateTemp(t, filepath.Join("routes", "index.tmpl"))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	w := newWatcher(dir)
+
+	if changed := w.changed(); len(changed) != 0 {
+		t.Logf("expected no changes, received %v", changed)
+		t.FailNow()
+	}
+
+	t.Run("created file is reported", func(t *testing.T) {
+		path := filepath.Join(dir, "templates", "base.tmpl")
+
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		err = os.WriteFile(path, []byte("{{ .Content }}"), 0644)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		changed := w.changed()
+		if len(changed) != 1 || changed[0] != path {
+			t.Logf("expected %v, received %v", path, changed)
+			t.Fail()
+		}
+	})
+
+	t.Run("modified file is reported", func(t *testing.T) {
+		path := filepath.Join(dir, "routes", "index.tmpl")
+
+		later := time.Now().Add(time.Minute)
+
+		err := os.Chtimes(path, later, later)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		changed := w.changed()
+		if len(changed) != 1 || changed[0] != path {
+			t.Logf("expected %v, received %v", path, changed)
+			t.Fail()
+		}
+	})
+
+	t.Run("removed file is reported", func(t *testing.T) {
+		path := filepath.Join(dir, "routes", "index.tmpl")
+
+		err := os.Remove(path)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		changed := w.changed()
+		if len(changed) != 1 || changed[0] != path {
+			t.Logf("expected %v, received %v", path, changed)
+			t.Fail()
+		}
+	})
+}
+
+func TestWatcherRun(t *testing.T) {
+	t.Run("burst of changes is merged into one batch", func(t *testing.T) {
+		dir, err := config.CreateTemp(t, filepath.Join("routes", "index.tmpl"))
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		w := newWatcher(dir)
+
+		stop := make(chan struct{})
+		batches := make(chan []string, 10)
+
+		go w.run(stop, func(changed []string) {
+			batches <- changed
+		})
+
+		for i := 0; i < 3; i++ {
+			path := filepath.Join(dir, "routes", "index.tmpl")
+
+			err := os.WriteFile(path, []byte(fmt.Sprint(i)), 0644)
+			if err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+
+			time.Sleep(WatchInterval)
+		}
+
+		select {
+		case batch := <-batches:
+			if len(batch) != 1 {
+				t.Logf("expected one changed file, received %v", batch)
+				t.Fail()
+			}
+		case <-time.After(5 * time.Second):
+			t.Log("expected a batch of changes")
+			t.Fail()
+		}
+
+		close(stop)
+
+		if len(batches) != 0 {
+			t.Logf("expected a single batch, received %v more", len(batches))
+			t.Fail()
+		}
+	})
+}
@@ -30,3 +30,11 @@ func Report() {
 		fmt.Println(v)
 	}
 }
+
+// Flush prints all available log messages and then discards them, so that
+// long-running routines do not print the same message twice.
+func Flush() {
+	Report()
+
+	logs = logs[:0]
+}